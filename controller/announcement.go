@@ -0,0 +1,461 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/yuin/goldmark"
+	"uhlig.it/kiosk/script"
+)
+
+//go:embed announcement.html.tmpl
+var announcementMarkup string
+
+var announcementTemplate = htmltemplate.Must(htmltemplate.New("announcement").Parse(announcementMarkup))
+
+const (
+	// defaultAnnouncementRefresh is how often an announcement is rendered again by default.
+	defaultAnnouncementRefresh = time.Minute
+	// dataTimeout limits how long fetching the data of an announcement may take.
+	dataTimeout = 10 * time.Second
+)
+
+// AnnouncementServer renders the announcements of tabs, and lets them be
+// edited via HTTP. Edits are kept in a directory, so that they survive
+// restarts, and take precedence over the script.
+type AnnouncementServer struct {
+	baseURL       string
+	dir           string
+	mutex         sync.RWMutex
+	announcements map[string]*script.Announcement
+	edits         map[string]*script.Announcement
+}
+
+// AnnouncementData is what the template of an announcement gets.
+type AnnouncementData struct {
+	Name     string
+	Time     time.Time
+	Hostname string
+	// Data is the JSON document at the data URL of the announcement, if it has one.
+	Data any
+}
+
+// announcementJSON is an announcement as read and written via HTTP and on disk.
+type announcementJSON struct {
+	Name     string `json:"name,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+	HTML     string `json:"html,omitempty"`
+	Data     string `json:"data,omitempty"`
+	Refresh  string `json:"refresh,omitempty"`
+	Edited   bool   `json:"edited,omitempty"`
+}
+
+// NewAnnouncementServer creates a server that is reachable by the browser at
+// baseURL, e.g. http://localhost:8011. It handles the paths below
+// /announcements. Edits are kept in dir; if it is empty, they are lost when
+// the kiosk stops.
+func NewAnnouncementServer(baseURL string, dir string) *AnnouncementServer {
+	return &AnnouncementServer{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		dir:           dir,
+		announcements: make(map[string]*script.Announcement),
+		edits:         make(map[string]*script.Announcement),
+	}
+}
+
+// Add serves the announcement of the tab with the given name, or the edit
+// of it kept earlier, and returns the URL of the page showing it.
+func (s *AnnouncementServer) Add(name string, announcement *script.Announcement) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.announcements[name] = announcement
+
+	if _, found := s.edits[name]; !found && s.dir != "" {
+		edit, err := s.load(name)
+
+		if err != nil {
+			return "", fmt.Errorf("could not load the edited announcement '%v': %w", name, err)
+		}
+
+		if edit != nil {
+			s.edits[name] = edit
+		}
+	}
+
+	return fmt.Sprintf("%v/announcements/%v/page", s.baseURL, url.PathEscape(name)), nil
+}
+
+func (s *AnnouncementServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the escaped path, as names may contain slashes
+	path := strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/announcements"), "/")
+
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, `{"error": "Only GET allowed here"}`, http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.serveList(w)
+		return
+	}
+
+	escapedName, page, _ := strings.Cut(path, "/")
+	name, err := url.PathUnescape(escapedName)
+
+	if err != nil || (page != "" && page != "page") {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mutex.RLock()
+	_, found := s.announcements[name]
+	s.mutex.RUnlock()
+
+	if !found {
+		http.Error(w, `{"error": "no such announcement"}`, http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case page != "" && r.Method == http.MethodGet:
+		s.servePage(w, r, name)
+	case page != "":
+		http.Error(w, `{"error": "Only GET allowed here"}`, http.StatusMethodNotAllowed)
+	case r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.describe(name))
+	case r.Method == http.MethodPut:
+		s.edit(w, r, name)
+	case r.Method == http.MethodDelete:
+		s.revert(w, name)
+	default:
+		http.Error(w, `{"error": "Only GET, PUT or DELETE allowed here"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *AnnouncementServer) serveList(w http.ResponseWriter) {
+	s.mutex.RLock()
+	names := make([]string, 0, len(s.announcements))
+
+	for name := range s.announcements {
+		names = append(names, name)
+	}
+
+	s.mutex.RUnlock()
+	slices.Sort(names)
+
+	list := make([]announcementJSON, 0, len(names))
+
+	for _, name := range names {
+		list = append(list, s.describe(name))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// current returns the edit of the announcement, if there is one, or the one of the script.
+func (s *AnnouncementServer) current(name string) (*script.Announcement, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if edit, found := s.edits[name]; found {
+		return edit, true
+	}
+
+	return s.announcements[name], false
+}
+
+func (s *AnnouncementServer) describe(name string) announcementJSON {
+	announcement, edited := s.current(name)
+	described := toJSON(announcement)
+	described.Name = name
+	described.Edited = edited
+
+	return described
+}
+
+// edit replaces the announcement with the one in the body of the request.
+func (s *AnnouncementServer) edit(w http.ResponseWriter, r *http.Request, name string) {
+	var edited announcementJSON
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&edited); err != nil {
+		http.Error(w, `{"error": "could not parse the announcement"}`, http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := fromJSON(edited)
+
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.mutex.Lock()
+	s.edits[name] = announcement
+	s.mutex.Unlock()
+
+	if err := s.save(name, announcement); err != nil {
+		log.Printf("could not keep the edited announcement '%v': %v", name, err)
+		http.Error(w, `{"error": "could not keep the announcement"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.describe(name))
+}
+
+// revert drops the edit of the announcement, so that the one of the script is shown again.
+func (s *AnnouncementServer) revert(w http.ResponseWriter, name string) {
+	s.mutex.Lock()
+	delete(s.edits, name)
+	s.mutex.Unlock()
+
+	if s.dir != "" {
+		if err := os.Remove(s.file(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not remove the edited announcement '%v': %v", name, err)
+			http.Error(w, `{"error": "could not remove the announcement"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *AnnouncementServer) servePage(w http.ResponseWriter, r *http.Request, name string) {
+	announcement, _ := s.current(name)
+	refresh := announcement.Refresh
+
+	if refresh == 0 {
+		refresh = defaultAnnouncementRefresh
+	}
+
+	content, err := renderAnnouncement(r.Context(), name, announcement)
+
+	if err != nil {
+		// the page refreshes, so that the announcement shows up once it can be rendered
+		content = htmltemplate.HTML(fmt.Sprintf("<p>%v</p><p><code>%v</code></p>",
+			htmltemplate.HTMLEscapeString(name),
+			htmltemplate.HTMLEscapeString(err.Error())))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	err = announcementTemplate.Execute(w, map[string]any{
+		"name":    name,
+		"refresh": int(refresh.Seconds()),
+		"content": content,
+	})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// renderAnnouncement executes the template of the announcement, and converts
+// the result to HTML if it is Markdown.
+func renderAnnouncement(ctx context.Context, name string, announcement *script.Announcement) (htmltemplate.HTML, error) {
+	data := AnnouncementData{Name: name, Time: time.Now()}
+	data.Hostname, _ = os.Hostname()
+
+	if announcement.Data != "" {
+		var err error
+
+		if data.Data, err = fetchData(ctx, announcement.Data); err != nil {
+			return "", fmt.Errorf("could not fetch the data of the announcement: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if announcement.HTML != "" {
+		tmpl, err := htmltemplate.New(name).Parse(announcement.HTML)
+
+		if err != nil {
+			return "", err
+		}
+
+		if err = tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+
+		return htmltemplate.HTML(buf.String()), nil
+	}
+
+	tmpl, err := template.New(name).Parse(announcement.Markdown)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	// HTML within the Markdown is left out, as the data may contain some
+	var html bytes.Buffer
+
+	if err := goldmark.Convert(buf.Bytes(), &html); err != nil {
+		return "", err
+	}
+
+	return htmltemplate.HTML(html.String()), nil
+}
+
+func fetchData(ctx context.Context, dataURL string) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dataURL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	response, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v answered with %v", dataURL, response.Status)
+	}
+
+	var data any
+
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("could not parse the JSON of %v: %w", dataURL, err)
+	}
+
+	return data, nil
+}
+
+// file is where the edit of the announcement is kept.
+func (s *AnnouncementServer) file(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".json")
+}
+
+func (s *AnnouncementServer) save(name string, announcement *script.Announcement) error {
+	if s.dir == "" {
+		log.Printf("the edited announcement '%v' is lost when the kiosk stops, as there is no directory to keep it in", name)
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(toJSON(announcement), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	// written at once, so that a crash does not leave half of it
+	tmp := s.file(name) + ".tmp"
+
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.file(name))
+}
+
+// load reads the edit of the announcement, or returns nil if there is none.
+func (s *AnnouncementServer) load(name string) (*script.Announcement, error) {
+	content, err := os.ReadFile(s.file(name))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var kept announcementJSON
+
+	if err := json.Unmarshal(content, &kept); err != nil {
+		return nil, err
+	}
+
+	return fromJSON(kept)
+}
+
+func toJSON(announcement *script.Announcement) announcementJSON {
+	described := announcementJSON{
+		Markdown: announcement.Markdown,
+		HTML:     announcement.HTML,
+		Data:     announcement.Data,
+	}
+
+	if announcement.Refresh != 0 {
+		described.Refresh = announcement.Refresh.String()
+	}
+
+	return described
+}
+
+func fromJSON(described announcementJSON) (*script.Announcement, error) {
+	announcement := &script.Announcement{
+		Markdown: described.Markdown,
+		HTML:     described.HTML,
+		Data:     described.Data,
+	}
+
+	if described.Refresh != "" {
+		refresh, err := time.ParseDuration(described.Refresh)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse '%v' as refresh of an announcement: %v", described.Refresh, err)
+		}
+
+		announcement.Refresh = refresh
+	}
+
+	if err := announcement.Validate(); err != nil {
+		return nil, err
+	}
+
+	return announcement, nil
+}
+
+// showAnnouncement opens the page of the announcement tab.
+func (k *Kiosk) showAnnouncement(ctx context.Context, tab *script.Tab) error {
+	if k.announcementServer == nil {
+		return errors.New("announcement tabs need the HTTP server of the kiosk")
+	}
+
+	url, err := k.announcementServer.Add(tab.Name, tab.Announcement)
+
+	if err != nil {
+		return err
+	}
+
+	return chromedp.Run(ctx, append(tab.Setup(), chromedp.Navigate(url))...)
+}
@@ -0,0 +1,151 @@
+package controller_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("AnnouncementServer", func() {
+	var dir string
+	var server *httptest.Server
+	var announcements *controller.AnnouncementServer
+
+	request := func(method string, url string, body string) (int, string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		response, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		content, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return response.StatusCode, string(content)
+	}
+
+	start := func() {
+		server = httptest.NewUnstartedServer(nil)
+		announcements = controller.NewAnnouncementServer("http://"+server.Listener.Addr().String(), dir)
+		server.Config.Handler = announcements
+		server.Start()
+		DeferCleanup(server.Close)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		start()
+	})
+
+	It("renders Markdown", func() {
+		url, err := announcements.Add("Welcome", &script.Announcement{Markdown: "# Welcome to {{ .Name }}\n\nSee <b>you</b>."})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(url).To(Equal(server.URL + "/announcements/Welcome/page"))
+
+		status, page := request(http.MethodGet, url, "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(page).To(ContainSubstring("<h1>Welcome to Welcome</h1>"))
+		Expect(page).To(ContainSubstring(`<meta http-equiv="refresh" content="60">`))
+		Expect(page).ToNot(ContainSubstring("<b>you</b>"))
+	})
+
+	It("renders HTML with the data", func() {
+		data := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"menu": "Soup & Salad"}`))
+		}))
+		DeferCleanup(data.Close)
+
+		url, err := announcements.Add("Lunch", &script.Announcement{HTML: "<h1>{{ .Data.menu }}</h1>", Data: data.URL})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, page := request(http.MethodGet, url, "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(page).To(ContainSubstring("<h1>Soup &amp; Salad</h1>"))
+	})
+
+	It("shows why an announcement cannot be rendered", func() {
+		url, err := announcements.Add("Lunch", &script.Announcement{HTML: "<h1>{{ .Data.menu }}</h1>", Data: server.URL + "/missing"})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, page := request(http.MethodGet, url, "")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(page).To(ContainSubstring("could not fetch the data of the announcement"))
+	})
+
+	It("keeps edits until they are reverted", func() {
+		url, err := announcements.Add("Welcome", &script.Announcement{Markdown: "Hello"})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, body := request(http.MethodPut, server.URL+"/announcements/Welcome", `{"markdown": "Closed today", "refresh": "10s"}`)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"name": "Welcome", "markdown": "Closed today", "refresh": "10s", "edited": true}`))
+
+		_, page := request(http.MethodGet, url, "")
+		Expect(page).To(ContainSubstring("<p>Closed today</p>"))
+		Expect(filepath.Join(dir, "Welcome.json")).To(BeARegularFile())
+
+		// a restarted kiosk shows the edit
+		start()
+		_, err = announcements.Add("Welcome", &script.Announcement{Markdown: "Hello"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, body = request(http.MethodGet, server.URL+"/announcements", "")
+		Expect(body).To(MatchJSON(`[{"name": "Welcome", "markdown": "Closed today", "refresh": "10s", "edited": true}]`))
+
+		status, _ = request(http.MethodDelete, server.URL+"/announcements/Welcome", "")
+		Expect(status).To(Equal(http.StatusNoContent))
+		Expect(filepath.Join(dir, "Welcome.json")).ToNot(BeAnExistingFile())
+
+		_, body = request(http.MethodGet, server.URL+"/announcements/Welcome", "")
+		Expect(body).To(MatchJSON(`{"name": "Welcome", "markdown": "Hello"}`))
+	})
+
+	It("rejects invalid edits", func() {
+		_, err := announcements.Add("Welcome", &script.Announcement{Markdown: "Hello"})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, body := request(http.MethodPut, server.URL+"/announcements/Welcome", `{"markdown": "Hello", "html": "Hello"}`)
+		Expect(status).To(Equal(http.StatusUnprocessableEntity))
+		Expect(body).To(MatchJSON(`{"error": "an announcement needs either 'markdown' or 'html'"}`))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	DescribeTable("names",
+		func(name string, escaped string) {
+			url, err := announcements.Add(name, &script.Announcement{Markdown: "Hello"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(url).To(Equal(server.URL + "/announcements/" + escaped + "/page"))
+
+			status, page := request(http.MethodGet, url, "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(page).To(ContainSubstring("<p>Hello</p>"))
+
+			status, _ = request(http.MethodPut, server.URL+"/announcements/"+escaped, `{"markdown": "Closed today"}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			_, body := request(http.MethodGet, server.URL+"/announcements/"+escaped, "")
+			Expect(body).To(MatchJSON(`{"name": "` + name + `", "markdown": "Closed today", "edited": true}`))
+			Expect(filepath.Join(dir, escaped+".json")).To(BeARegularFile())
+		},
+		Entry("with a percent sign", "100% uptime", "100%25%20uptime"),
+		Entry("with a slash", "Ops/NOC", "Ops%2FNOC"),
+		Entry("with spaces", "Front desk", "Front%20desk"),
+	)
+
+	It("does not know other announcements", func() {
+		status, _ := request(http.MethodPut, server.URL+"/announcements/Welcome", `{"markdown": "Hello"}`)
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+})
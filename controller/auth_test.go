@@ -0,0 +1,85 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Auth", func() {
+	var server *httptest.Server
+	var trial *controller.Trial
+	var challenges atomic.Int32
+
+	parse := func(auth string) *script.Tab {
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Status
+  auth:
+%v
+  script:
+    - go: %v
+    - wait: text:Welcome
+      timeout: 3s
+`, auth, server.URL)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+
+		return tabs[0]
+	}
+
+	BeforeEach(func() {
+		needBrowser()
+
+		// the certificate of the test server is self-signed
+		trial = newTrial().WithFlag("ignore-certificate-errors", true)
+
+		challenges.Store(0)
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username, password, ok := r.BasicAuth(); !ok || username != "kiosk" || password != "s3cr3t" {
+				challenges.Add(1)
+				w.Header().Set("WWW-Authenticate", `Basic realm="status"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+				return
+			}
+
+			fmt.Fprint(w, `<!doctype html><html><body><p>Welcome</p></body></html>`)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("provides the credentials", func() {
+		report, err := trial.Run(parse(`
+    username: kiosk
+    password: s3cr3t
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Error).To(BeEmpty())
+	})
+
+	It("gives up on rejected credentials", func() {
+		report, err := trial.Run(parse(`
+    username: kiosk
+    password: wrong
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.FailedStep).To(Equal(2))
+		Expect(challenges.Load()).To(BeNumerically("<=", 2))
+	})
+
+	It("keeps the credentials from other origins", func() {
+		report, err := trial.Run(parse(`
+    username: kiosk
+    password: s3cr3t
+    origin: https://elsewhere.example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.FailedStep).To(Equal(2))
+	})
+})
@@ -0,0 +1,37 @@
+package controller_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+func TestController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controller Suite")
+}
+
+// noBrowser tells why there is no browser to run the specs that need one.
+var noBrowser error
+
+var _ = BeforeSuite(func() {
+	_, noBrowser = newTrial().Run(&script.Tab{Name: "probe"})
+})
+
+// newTrial returns a trial that runs in the test environment; running as root,
+// e.g. in a container, requires disabling the sandbox.
+func newTrial() *controller.Trial {
+	return controller.NewTrial().WithTimeout(10*time.Second).WithFlag("no-sandbox", true)
+}
+
+// needBrowser skips the spec if there is no browser to run it.
+func needBrowser() {
+	if noBrowser != nil {
+		Skip(fmt.Sprintf("no browser available: %v", noBrowser))
+	}
+}
@@ -2,11 +2,21 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 	"uhlig.it/kiosk/script"
@@ -14,32 +24,101 @@ import (
 )
 
 type StatusUpdate struct {
+	// Screen is the name of the screen of the kiosk; empty for the default screen.
+	Screen         string                     `json:"screen,omitempty"`
 	IsTabSwitching bool                       `json:"isTabSwitching"`
 	CurrentTab     string                     `json:"currentTab"`
 	DisplayStati   []*videocore.DisplayStatus `json:"displayStati"`
+	Rules          []RuleStatus               `json:"rules,omitempty"`
+	Takeover       string                     `json:"takeover,omitempty"`
 }
 
+// RuleStatus tells how often a block or rewrite rule of a tab applied.
+type RuleStatus struct {
+	Tab  string `json:"tab"`
+	Rule string `json:"rule"`
+	Hits int64  `json:"hits"`
+}
+
+const (
+	retryAttempts = 5
+	retryDelay    = 2 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// Kiosk shows tabs in a browser and switches between them. Its methods may be
+// called from several goroutines; they take turns, so that a tab switch, a
+// change of the tabs and a takeover do not interleave.
 type Kiosk struct {
-	statusUpdates    chan StatusUpdate
-	currentTab       target.ID
-	allContexts      []context.Context
-	images           map[target.ID]*Image
-	quitTabSwitching chan struct{}
-	interval         time.Duration
-	fullScreen       bool
-	headless         bool
-	cancelAllocator  context.CancelFunc
-	cancelContext    context.CancelFunc
-	extraFlags       map[string]interface{}
+	mutex              sync.Mutex
+	name               string
+	statusUpdates      chan StatusUpdate
+	currentTab         target.ID
+	allContexts        []context.Context
+	tabs               map[target.ID]*script.Tab
+	layouts            map[target.ID]context.CancelFunc
+	retrying           map[*script.Tab]bool
+	mediaServer        *MediaServer
+	announcementServer *AnnouncementServer
+	images             map[target.ID]*Image
+	quitTabSwitching   chan struct{}
+	switcherDone       chan struct{}
+	takeover           *takenOver
+	interval           time.Duration
+	fullScreen         bool
+	headless           bool
+	userDataDir        string
+	remoteURL          string
+	targetsFile        string
+	adoptableTargets   []target.ID
+	browserContext     context.Context
+	spareContext       context.Context
+	cancelAllocator    context.CancelFunc
+	cancelContext      context.CancelFunc
+	extraFlags         map[string]interface{}
 }
 
 func NewKiosk() *Kiosk {
+	// tab switching starts paused
+	stopped := make(chan struct{})
+	close(stopped)
+
 	return &Kiosk{
-		images:     make(map[target.ID]*Image),
-		extraFlags: make(map[string]interface{}),
+		tabs:             make(map[target.ID]*script.Tab),
+		layouts:          make(map[target.ID]context.CancelFunc),
+		retrying:         make(map[*script.Tab]bool),
+		images:           make(map[target.ID]*Image),
+		quitTabSwitching: stopped,
+		switcherDone:     stopped,
+		extraFlags:       make(map[string]interface{}),
 	}
 }
 
+// WithName names the screen the kiosk shows its tabs on. Status updates
+// carry the name, so that several kiosks can share one channel.
+func (k *Kiosk) WithName(name string) *Kiosk {
+	k.name = name
+	return k
+}
+
+// WithWindow places the window of the browser at x, y on the desktop, e.g. on
+// a second display. A zero width or height keeps the size the browser picks.
+func (k *Kiosk) WithWindow(x, y, width, height int64) *Kiosk {
+	if x != 0 || y != 0 {
+		k.extraFlags["window-position"] = fmt.Sprintf("%d,%d", x, y)
+	}
+
+	if width != 0 && height != 0 {
+		k.extraFlags["window-size"] = fmt.Sprintf("%d,%d", width, height)
+	}
+
+	return k
+}
+
+func (k *Kiosk) Name() string {
+	return k.name
+}
+
 func (k *Kiosk) WithInterval(interval time.Duration) *Kiosk {
 	k.interval = interval
 	return k
@@ -55,26 +134,201 @@ func (k *Kiosk) WithHeadless(headless bool) *Kiosk {
 	return k
 }
 
+// WithUserDataDir keeps the profile of the browser in dir, so that cookies
+// and storage survive restarts. By default, a temporary directory is used.
+func (k *Kiosk) WithUserDataDir(dir string) *Kiosk {
+	k.userDataDir = dir
+	return k
+}
+
+// WithRemoteDebuggingURL attaches to a browser that is already running with
+// remote debugging enabled, e.g. http://localhost:9222, instead of starting
+// one. It keeps running when the kiosk is closed. Flags, the user data dir
+// and the screen settings are up to whoever started the browser.
+func (k *Kiosk) WithRemoteDebuggingURL(url string) *Kiosk {
+	k.remoteURL = url
+	return k
+}
+
+// WithTargetsFile records the tabs the kiosk opens in a remote browser in
+// file, so that a restarted kiosk takes them over again before opening new
+// ones. Other tabs of the browser are left alone.
+func (k *Kiosk) WithTargetsFile(file string) *Kiosk {
+	k.targetsFile = file
+	return k
+}
+
 func (k *Kiosk) WithFlag(key string, value interface{}) *Kiosk {
 	k.extraFlags[key] = value
 	return k
 }
 
+// WithMediaServer serves the files of media tabs by the given server, which
+// the browser needs to be able to reach.
+func (k *Kiosk) WithMediaServer(server *MediaServer) *Kiosk {
+	k.mediaServer = server
+	return k
+}
+
+// WithAnnouncementServer renders the announcements of tabs by the given
+// server, which the browser needs to be able to reach.
+func (k *Kiosk) WithAnnouncementServer(server *AnnouncementServer) *Kiosk {
+	k.announcementServer = server
+	return k
+}
+
 func (k *Kiosk) WithStatusUpdates(statusUpdatesChannel chan StatusUpdate) *Kiosk {
 	k.statusUpdates = statusUpdatesChannel
 	return k
 }
 
+// NewTab opens the tab and puts it into the rotation. During a takeover, the
+// takeover stays in front.
 func (k *Kiosk) NewTab(tab *script.Tab) error {
-	if len(k.allContexts) == 0 {
-		return k.createFirstTab(tab)
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if err := k.newTab(tab); err != nil {
+		return err
 	}
 
-	return k.createAdditionalTab(tab)
+	k.recordTargets()
+
+	return k.activateTakeover()
+}
+
+// newTab opens the tab and puts it into the rotation, unless its policy on
+// errors says otherwise. The caller holds the lock; it is released while
+// waiting to retry, so that the kiosk keeps switching tabs and answering in
+// the meantime.
+func (k *Kiosk) newTab(tab *script.Tab) error {
+	ctx := k.newTabContext()
+	err := k.createTab(ctx, tab)
+
+	if err == nil {
+		k.addTab(ctx, tab)
+		return nil
+	}
+
+	switch tab.OnError {
+	case script.OnErrorSkip:
+		log.Printf("skipping tab '%v': %v", tab.Name, err)
+		k.discardTabContext(ctx)
+
+		return nil
+	case script.OnErrorPlaceholder:
+		log.Printf("showing placeholder for tab '%v': %v", tab.Name, err)
+
+		if placeholderErr := k.showPlaceholder(ctx, tab, err); placeholderErr != nil {
+			k.discardTabContext(ctx)
+			return fmt.Errorf("could not show placeholder for tab '%v': %v", tab.Name, placeholderErr)
+		}
+
+		k.addTab(ctx, tab)
+		k.retrying[tab] = true
+		go k.retryForever(ctx, tab)
+
+		return nil
+	case script.OnErrorRetry:
+		delay := retryDelay
+
+		for attempt := 2; attempt <= retryAttempts; attempt++ {
+			log.Printf("attempt %v of %v for tab '%v' failed: %v", attempt-1, retryAttempts, tab.Name, err)
+			k.mutex.Unlock()
+			time.Sleep(delay)
+			k.mutex.Lock()
+			delay *= 2
+
+			if err = k.createTab(ctx, tab); err == nil {
+				k.addTab(ctx, tab)
+				return nil
+			}
+		}
+
+		k.discardTabContext(ctx)
+
+		return err
+	default:
+		k.discardTabContext(ctx)
+
+		return err
+	}
+}
+
+// ReplaceTabs opens the given tabs in place of the current ones, e.g. after
+// the script changed. If one of them fails, the current tabs are kept.
+func (k *Kiosk) ReplaceTabs(tabs []*script.Tab) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	wasTabSwitching := k.isTabSwitching()
+
+	if wasTabSwitching {
+		k.pauseTabSwitching()
+	}
+
+	// the current tabs stay in the rotation until the new ones are open, as
+	// the lock is released while a tab waits to retry
+	oldContexts := slices.Clone(k.allContexts)
+
+	for _, tab := range tabs {
+		if err := k.newTab(tab); err != nil {
+			for _, ctx := range k.allContexts {
+				if !slices.Contains(oldContexts, ctx) {
+					k.discardTab(ctx)
+				}
+			}
+
+			k.allContexts = oldContexts
+
+			if wasTabSwitching {
+				k.startTabSwitching()
+			}
+
+			return err
+		}
+	}
+
+	// the new tabs are open, so closing the old ones does not close the browser
+	for _, ctx := range oldContexts {
+		k.discardTab(ctx)
+	}
+
+	k.allContexts = slices.DeleteFunc(k.allContexts, func(ctx context.Context) bool {
+		return slices.Contains(oldContexts, ctx)
+	})
+
+	k.recordTargets()
+
+	k.currentTab = ""
+
+	// a takeover stays in front of the new tabs, and hands back to the first of them
+	if k.takeover != nil {
+		if err := k.activateTakeover(); err != nil {
+			return err
+		}
+	} else if len(k.allContexts) > 0 {
+		if err := k.switchToTab(k.allContexts[0]); err != nil {
+			return err
+		}
+	}
+
+	if wasTabSwitching {
+		k.startTabSwitching()
+	}
+
+	return nil
 }
 
 func (k *Kiosk) NextTab() error {
-	k.PauseTabSwitching()
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.takeover != nil {
+		return ErrTakenOver
+	}
+
+	k.pauseTabSwitching()
 
 	nextContext, err := k.findNextTab(true)
 
@@ -92,7 +346,14 @@ func (k *Kiosk) NextTab() error {
 }
 
 func (k *Kiosk) PreviousTab() error {
-	k.PauseTabSwitching()
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.takeover != nil {
+		return ErrTakenOver
+	}
+
+	k.pauseTabSwitching()
 
 	previousContext, err := k.findNextTab(false)
 
@@ -110,7 +371,14 @@ func (k *Kiosk) PreviousTab() error {
 }
 
 func (k *Kiosk) SwitchToTab(targetID string) error {
-	k.PauseTabSwitching()
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.takeover != nil {
+		return ErrTakenOver
+	}
+
+	k.pauseTabSwitching()
 
 	nextContext, err := k.findTab(target.ID(targetID))
 
@@ -122,34 +390,202 @@ func (k *Kiosk) SwitchToTab(targetID string) error {
 }
 
 func (k *Kiosk) StartTabSwitching() {
-	k.quitTabSwitching = make(chan struct{})
-	go k.switchTabsForever()
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
 
-	k.statusUpdates <- StatusUpdate{
-		IsTabSwitching: k.IsTabSwitching(),
+	k.startTabSwitching()
+}
+
+func (k *Kiosk) startTabSwitching() {
+	// the takeover stays until it is handed back
+	if k.takeover != nil {
+		k.takeover.wasTabSwitching = true
+		return
 	}
+
+	if !k.isTabSwitching() {
+		k.quitTabSwitching = make(chan struct{})
+		k.switcherDone = make(chan struct{})
+		go k.switchTabsForever(k.quitTabSwitching, k.switcherDone, k.currentShowTime())
+	}
+
+	k.notify(StatusUpdate{
+		Screen:         k.name,
+		IsTabSwitching: k.isTabSwitching(),
+	})
 }
 
+// PauseTabSwitching stops switching tabs, and returns once no more switch
+// can happen.
 func (k *Kiosk) PauseTabSwitching() {
+	k.mutex.Lock()
+	done := k.pauseTabSwitching()
+	k.mutex.Unlock()
+
+	<-done
+}
+
+// pauseTabSwitching stops switching tabs, and returns a channel that is
+// closed once the switcher exited. Callers holding the lock cannot wait for
+// it, but they need not: the switcher checks whether it was paused after
+// taking the lock.
+func (k *Kiosk) pauseTabSwitching() <-chan struct{} {
+	if k.takeover != nil {
+		k.takeover.wasTabSwitching = false
+	}
+
 	if !isClosed(k.quitTabSwitching) {
 		close(k.quitTabSwitching)
 	}
 
-	k.statusUpdates <- StatusUpdate{
-		IsTabSwitching: k.IsTabSwitching(),
-	}
+	k.notify(StatusUpdate{
+		Screen:         k.name,
+		IsTabSwitching: k.isTabSwitching(),
+	})
+
+	return k.switcherDone
 }
 
 func (k *Kiosk) IsTabSwitching() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return k.isTabSwitching()
+}
+
+func (k *Kiosk) isTabSwitching() bool {
 	return !isClosed(k.quitTabSwitching)
 }
 
+// ClearProfile removes all cookies, the cache and the storage of the origins
+// of the tabs from the profile. The scripts of the tabs then run again in the
+// background, so that they log in anew; tabs showing a placeholder are left
+// to their retries. The browser keeps running, as its files must not be
+// removed while it uses them.
+func (k *Kiosk) ClearProfile() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.browserContext == nil {
+		return nil
+	}
+
+	actions := []chromedp.Action{network.ClearBrowserCookies(), network.ClearBrowserCache()}
+
+	for _, origin := range k.origins() {
+		actions = append(actions, storage.ClearDataForOrigin(origin, "all"))
+	}
+
+	if err := chromedp.Run(k.browserContext, actions...); err != nil {
+		return fmt.Errorf("could not clear the profile: %w", err)
+	}
+
+	go k.recreateTabs(slices.Clone(k.allContexts))
+
+	return nil
+}
+
+// recreateTabs runs the scripts of the tabs of contexts again, one after the
+// other, so that the tabs keep switching in between.
+func (k *Kiosk) recreateTabs(contexts []context.Context) {
+	for _, ctx := range contexts {
+		if err := k.recreateTab(ctx); err != nil {
+			log.Printf("could not run the script again after clearing the profile: %v", err)
+		}
+	}
+}
+
+// recreateTab runs the script of the tab of ctx again, unless the tab was
+// replaced in the meantime, or is retried anyway.
+func (k *Kiosk) recreateTab(ctx context.Context) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	tab, found := k.tabs[chromedp.FromContext(ctx).Target.TargetID]
+
+	if !found || !slices.Contains(k.allContexts, ctx) || k.retrying[tab] {
+		return nil
+	}
+
+	return k.createTab(ctx, tab)
+}
+
+// origins of the URLs of all tabs and panes, without duplicates.
+func (k *Kiosk) origins() []string {
+	var origins []string
+
+	for _, ctx := range k.allContexts {
+		tab := k.tabs[chromedp.FromContext(ctx).Target.TargetID]
+		urls := []string{tab.URL()}
+
+		if tab.Layout != nil {
+			for _, pane := range tab.Layout.Panes {
+				urls = append(urls, pane.URL())
+			}
+		}
+
+		for _, rawURL := range urls {
+			u, err := url.Parse(rawURL)
+
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				continue
+			}
+
+			if origin := u.Scheme + "://" + u.Host; !slices.Contains(origins, origin) {
+				origins = append(origins, origin)
+			}
+		}
+	}
+
+	return origins
+}
+
+// Status describes the tab switching and how often the rules of the tabs
+// applied, in the order of the tabs.
+func (k *Kiosk) Status() StatusUpdate {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	status := StatusUpdate{
+		Screen:         k.name,
+		IsTabSwitching: k.isTabSwitching(),
+		CurrentTab:     k.currentTab.String(),
+		Rules:          []RuleStatus{},
+	}
+
+	if k.takeover != nil {
+		status.Takeover = k.takeover.String()
+	}
+
+	for _, ctx := range k.allContexts {
+		tab := k.tabs[chromedp.FromContext(ctx).Target.TargetID]
+
+		for _, rule := range tab.Rules() {
+			status.Rules = append(status.Rules, RuleStatus{Tab: tab.Name, Rule: rule.String(), Hits: rule.Hits()})
+		}
+	}
+
+	return status
+}
+
+// Close closes the browser, unless the kiosk attached to one that was
+// running already. Such a browser keeps its tabs for the next kiosk.
 func (k *Kiosk) Close() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.remoteURL != "" || k.browserContext == nil {
+		return
+	}
+
 	k.cancelAllocator()
 	k.cancelContext()
 }
 
 func (k *Kiosk) GetImage(id string) (*Image, bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
 	img, found := k.images[target.ID(id)]
 
 	if found {
@@ -160,6 +596,9 @@ func (k *Kiosk) GetImage(id string) (*Image, bool) {
 }
 
 func (k *Kiosk) ImageIDs() (images []string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
 	for _, ctx := range k.allContexts {
 		tabID := chromedp.FromContext(ctx).Target.TargetID
 		images = append(images, tabID.String())
@@ -168,7 +607,181 @@ func (k *Kiosk) ImageIDs() (images []string) {
 	return
 }
 
-func (k *Kiosk) createFirstTab(tab *script.Tab) error {
+// addTab puts the tab of ctx into the rotation.
+func (k *Kiosk) addTab(ctx context.Context, tab *script.Tab) {
+	k.allContexts = append(k.allContexts, ctx)
+	k.tabs[chromedp.FromContext(ctx).Target.TargetID] = tab
+}
+
+// newTabContext hands out the context for a new tab. The first one starts
+// the browser; a context discarded earlier is reused before opening another tab.
+func (k *Kiosk) newTabContext() context.Context {
+	if k.spareContext != nil {
+		ctx := k.spareContext
+		k.spareContext = nil
+
+		return ctx
+	}
+
+	if k.browserContext == nil {
+		return k.startBrowser()
+	}
+
+	if id, found := k.nextAdoptableTarget(); found {
+		ctx, _ := chromedp.NewContext(k.rootContext(), chromedp.WithTargetID(id))
+		return ctx
+	}
+
+	ctx, _ := chromedp.NewContext(k.rootContext())
+
+	return ctx
+}
+
+// nextAdoptableTarget hands out the tabs that a remote browser kept from an
+// earlier run of the kiosk, other than the one the browser context took.
+func (k *Kiosk) nextAdoptableTarget() (target.ID, bool) {
+	if len(k.adoptableTargets) == 0 {
+		return "", false
+	}
+
+	id := k.adoptableTargets[0]
+	k.adoptableTargets = k.adoptableTargets[1:]
+
+	return id, true
+}
+
+// CloseLeftoverTabs closes the tabs that a remote browser kept from an earlier
+// run of the kiosk, but that were not taken over, e.g. as the script has fewer
+// tabs now.
+func (k *Kiosk) CloseLeftoverTabs() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	for _, id := range k.adoptableTargets {
+		if err := chromedp.Run(k.rootContext(), closeTarget(id)); err != nil {
+			log.Printf("could not close the leftover tab %v: %v", id, err)
+		}
+	}
+
+	k.adoptableTargets = nil
+	k.recordTargets()
+}
+
+// closeTarget closes the tab with the given ID, which none of the contexts of
+// the kiosk is attached to.
+func closeTarget(id target.ID) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		return target.CloseTarget(id).Do(cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Browser))
+	})
+}
+
+// recordTargets writes the IDs of the tabs of the kiosk to the targets file,
+// along with the ones left to adopt, so that a restarted kiosk finds them.
+func (k *Kiosk) recordTargets() {
+	if k.remoteURL == "" || k.targetsFile == "" {
+		return
+	}
+
+	var lines []string
+	contexts := append(slices.Clone(k.allContexts), k.spareContext)
+
+	if k.takeover != nil {
+		contexts = append(contexts, k.takeover.ctx)
+	}
+
+	for _, ctx := range contexts {
+		if ctx != nil && chromedp.FromContext(ctx).Target != nil {
+			lines = append(lines, chromedp.FromContext(ctx).Target.TargetID.String())
+		}
+	}
+
+	for _, id := range k.adoptableTargets {
+		lines = append(lines, id.String())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.targetsFile), 0o700); err != nil {
+		log.Printf("could not record the tabs of the browser: %v", err)
+		return
+	}
+
+	if err := os.WriteFile(k.targetsFile, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		log.Printf("could not record the tabs of the browser: %v", err)
+	}
+}
+
+// recordedTargets are the tabs of the remote browser that the kiosk opened
+// earlier and that are still open, in the order they were recorded.
+func (k *Kiosk) recordedTargets(ctx context.Context) []target.ID {
+	if k.targetsFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(k.targetsFile)
+
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not read the recorded tabs of the browser: %v", err)
+		}
+
+		return nil
+	}
+
+	targets, err := chromedp.Targets(ctx)
+
+	if err != nil {
+		log.Printf("could not list the tabs of the browser: %v", err)
+		return nil
+	}
+
+	var recorded []target.ID
+
+	for _, line := range strings.Fields(string(content)) {
+		id := target.ID(line)
+
+		if slices.ContainsFunc(targets, func(info *target.Info) bool { return info.TargetID == id && info.Type == "page" }) {
+			recorded = append(recorded, id)
+		}
+	}
+
+	return recorded
+}
+
+// discardTabContext closes the tab of ctx. The browser's first tab cannot be
+// closed without closing the browser, so it is kept for the next tab instead.
+func (k *Kiosk) discardTabContext(ctx context.Context) {
+	// the panes of a layout stop being maintained
+	if cancel, found := k.layouts[chromedp.FromContext(ctx).Target.TargetID]; found {
+		cancel()
+		delete(k.layouts, chromedp.FromContext(ctx).Target.TargetID)
+	}
+
+	if ctx != k.browserContext {
+		script.EndSession(ctx)
+		chromedp.Cancel(ctx)
+		return
+	}
+
+	err := chromedp.Run(ctx, chromedp.Navigate("about:blank"))
+
+	if err != nil {
+		log.Printf("could not reset the first tab: %v", err)
+	}
+
+	k.spareContext = ctx
+}
+
+// discardTab forgets the tab of ctx and its screenshot, and closes it.
+func (k *Kiosk) discardTab(ctx context.Context) {
+	delete(k.tabs, chromedp.FromContext(ctx).Target.TargetID)
+	delete(k.images, chromedp.FromContext(ctx).Target.TargetID)
+	k.discardTabContext(ctx)
+}
+
+func (k *Kiosk) startBrowser() context.Context {
+	if k.remoteURL != "" {
+		return k.attachBrowser()
+	}
+
 	allocatorOptions := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("start-fullscreen", k.fullScreen),
 		chromedp.Flag("kiosk", k.fullScreen),
@@ -176,6 +789,14 @@ func (k *Kiosk) createFirstTab(tab *script.Tab) error {
 		chromedp.Flag("enable-automation", false),
 	)
 
+	if k.userDataDir != "" {
+		allocatorOptions = append(allocatorOptions,
+			chromedp.UserDataDir(k.userDataDir),
+			// being stopped looks like a crash to the browser
+			chromedp.Flag("hide-crash-restore-bubble", true),
+		)
+	}
+
 	for key, value := range k.extraFlags {
 		allocatorOptions = append(allocatorOptions, chromedp.Flag(key, value))
 	}
@@ -192,17 +813,60 @@ func (k *Kiosk) createFirstTab(tab *script.Tab) error {
 	)
 
 	k.cancelContext = cancelContext
+	k.browserContext = ctx
 
-	return k.createTab(ctx, tab)
+	return ctx
 }
 
-func (k *Kiosk) createAdditionalTab(tab *script.Tab) error {
-	ctx, _ := chromedp.NewContext(k.rootContext())
-	return k.createTab(ctx, tab)
+// attachBrowser connects to the browser at the remote debugging URL. The
+// returned context takes over the first of the tabs the kiosk opened in an
+// earlier run, or opens a new one.
+func (k *Kiosk) attachBrowser() context.Context {
+	var options []chromedp.RemoteAllocatorOption
+
+	// the URL of a specific browser cannot be looked up
+	if strings.Contains(k.remoteURL, "/devtools/browser/") {
+		options = append(options, chromedp.NoModifyURL)
+	}
+
+	allocCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), k.remoteURL, options...)
+
+	k.cancelAllocator = cancelAllocator
+
+	// connected to the browser, but not to any of its tabs
+	browserCtx, cancelContext := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(func(msg string, values ...interface{}) {
+			log.Printf(msg, values...)
+		}),
+	)
+
+	k.cancelContext = cancelContext
+	k.adoptableTargets = k.recordedTargets(browserCtx)
+
+	// even the first context opens a new tab of a remote browser, unless told which one to take
+	if id, found := k.nextAdoptableTarget(); found {
+		k.browserContext, _ = chromedp.NewContext(browserCtx, chromedp.WithTargetID(id))
+	} else {
+		k.browserContext, _ = chromedp.NewContext(browserCtx)
+	}
+
+	return k.browserContext
 }
 
 func (k *Kiosk) createTab(ctx context.Context, tab *script.Tab) error {
-	err := chromedp.Run(ctx, tab.Actions()...)
+	var err error
+
+	switch {
+	case tab.Layout != nil:
+		err = k.createLayout(ctx, tab)
+	case tab.Media != nil:
+		err = k.showMedia(ctx, tab)
+	case tab.Announcement != nil:
+		err = k.showAnnouncement(ctx, tab)
+	default:
+		err = chromedp.Run(ctx, append(tab.Setup(), tab.Actions()...)...)
+	}
 
 	if err != nil {
 		return fmt.Errorf("could not create tab '%v': %v", tab.Name, err)
@@ -214,45 +878,150 @@ func (k *Kiosk) createTab(ctx context.Context, tab *script.Tab) error {
 		return fmt.Errorf("could not take screenshot of tab '%v': %v", tab.Name, err)
 	}
 
-	k.allContexts = append(k.allContexts, ctx)
-
 	return nil
 }
 
+// retryForever runs the steps of a tab that currently shows a placeholder
+// until they succeed.
+func (k *Kiosk) retryForever(ctx context.Context, tab *script.Tab) {
+	defer func() {
+		k.mutex.Lock()
+		delete(k.retrying, tab)
+		k.mutex.Unlock()
+	}()
+
+	delay := retryDelay
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		replaced, err := k.retry(ctx, tab)
+
+		if replaced {
+			return
+		}
+
+		if err == nil {
+			log.Printf("tab '%v' recovered", tab.Name)
+			return
+		}
+
+		log.Printf("tab '%v' still failing: %v", tab.Name, err)
+
+		if delay < maxRetryDelay {
+			delay *= 2
+		}
+	}
+}
+
+// retry runs the steps of the tab of ctx again, unless the tab was replaced
+// in the meantime.
+func (k *Kiosk) retry(ctx context.Context, tab *script.Tab) (replaced bool, err error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	// the tab may have been replaced in the meantime, even by one reusing ctx
+	if !slices.Contains(k.allContexts, ctx) || k.tabs[chromedp.FromContext(ctx).Target.TargetID] != tab {
+		return true, nil
+	}
+
+	return false, k.createTab(ctx, tab)
+}
+
 func (k *Kiosk) rootContext() context.Context {
-	return k.allContexts[0]
+	return k.browserContext
 }
 
 func (k *Kiosk) setCurrentTab(id target.ID) {
 	(*k).currentTab = id
-	k.statusUpdates <- StatusUpdate{
+	k.notify(StatusUpdate{
+		Screen:     k.name,
 		CurrentTab: id.String(),
+	})
+}
+
+// notify sends the update, unless the channel is full. Status updates are
+// sent while holding the lock, so nobody listening must not stall the kiosk.
+func (k *Kiosk) notify(update StatusUpdate) {
+	select {
+	case k.statusUpdates <- update:
+	default:
 	}
 }
 
-func (k *Kiosk) switchTabsForever() error {
-	ticker := time.NewTicker(k.interval)
+// switchTabsForever switches to the next tab once the current one was shown
+// for showTime, until quit is closed, and closes done when it exits.
+func (k *Kiosk) switchTabsForever(quit <-chan struct{}, done chan<- struct{}, showTime time.Duration) {
+	defer close(done)
+
+	timer := time.NewTimer(showTime)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			nextContext, err := k.findNextTab(true)
+		case <-timer.C:
+			showTime, err := k.switchToNextTab(quit)
 
 			if err != nil {
-				return err
+				log.Printf("stopped switching tabs: %v", err)
+				return
 			}
 
-			err = k.switchToTab(nextContext)
+			timer.Reset(showTime)
+		case <-quit:
+			return
+		}
+	}
+}
 
-			if err != nil {
-				return err
-			}
-		case <-k.quitTabSwitching:
-			ticker.Stop()
+// switchToNextTab switches to the next tab, and returns how long to show it.
+func (k *Kiosk) switchToNextTab(quit <-chan struct{}) (time.Duration, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
 
-			return nil
-		}
+	// paused while waiting for the lock
+	if isClosed(quit) {
+		return k.interval, nil
+	}
+
+	nextContext, err := k.findNextTab(true)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = k.switchToTab(nextContext); err != nil {
+		return 0, err
+	}
+
+	return k.currentShowTime(), nil
+}
+
+// currentShowTime tells how long the current tab is shown: the interval, or
+// as long as its media take to be shown once.
+func (k *Kiosk) currentShowTime() time.Duration {
+	tab, found := k.tabs[k.currentTab]
+
+	if !found || tab.Media == nil {
+		return k.interval
+	}
+
+	showTime, err := showTime(tab.Media)
+
+	if err != nil {
+		log.Printf("showing tab '%v' for the interval: %v", tab.Name, err)
+		return k.interval
 	}
+
+	if showTime == 0 {
+		return k.interval
+	}
+
+	return showTime
 }
 
 func (k *Kiosk) switchToTab(targetContext context.Context) error {
@@ -316,7 +1085,7 @@ func (k *Kiosk) findNextTab(forward bool) (context.Context, error) {
 		if k.currentTab == "" || tabID == k.currentTab {
 			// grab the context of the next tab or cycle to the beginning
 			if i == len(k.allContexts)-1 {
-				return k.rootContext(), nil
+				return k.allContexts[0], nil
 			} else {
 				return k.allContexts[i+1], nil
 			}
@@ -0,0 +1,320 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Kiosk", func() {
+	It("starts with tab switching paused", func() {
+		kiosk := controller.NewKiosk()
+		Expect(kiosk.IsTabSwitching()).To(BeFalse())
+
+		// pausing a paused kiosk returns at once
+		kiosk.PauseTabSwitching()
+		Expect(kiosk.IsTabSwitching()).To(BeFalse())
+	})
+
+	It("has no profile to clear before it opens a tab", func() {
+		Expect(controller.NewKiosk().ClearProfile()).To(Succeed())
+	})
+
+	It("is paused and resumed from several goroutines", func() {
+		kiosk := controller.NewKiosk().
+			WithInterval(time.Millisecond).
+			WithStatusUpdates(make(chan controller.StatusUpdate))
+
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				kiosk.StartTabSwitching()
+				kiosk.Status()
+				kiosk.PauseTabSwitching()
+			}()
+		}
+
+		wg.Wait()
+		Expect(kiosk.IsTabSwitching()).To(BeFalse())
+	})
+
+	Context("taken over", func() {
+		var server *httptest.Server
+		var kiosk *controller.Kiosk
+
+		tabs := func(names ...string) []*script.Tab {
+			var markup string
+
+			for _, name := range names {
+				markup += fmt.Sprintf("- name: %v\n  script:\n    - go: %v/%v\n", name, server.URL, name)
+			}
+
+			tabs, err := script.Parse([]byte(markup))
+			Expect(err).ToNot(HaveOccurred())
+
+			return tabs
+		}
+
+		BeforeEach(func() {
+			needBrowser()
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "<h1>%v</h1>", r.URL.Path)
+			}))
+			DeferCleanup(server.Close)
+
+			kiosk = controller.NewKiosk().
+				WithHeadless(true).
+				WithFlag("no-sandbox", true).
+				WithInterval(time.Hour).
+				WithStatusUpdates(make(chan controller.StatusUpdate))
+			DeferCleanup(kiosk.Close)
+
+			for _, tab := range tabs("first", "second") {
+				Expect(kiosk.NewTab(tab)).To(Succeed())
+			}
+
+			kiosk.StartTabSwitching()
+			Expect(kiosk.TakeOver(controller.Takeover{Message: "Please leave the building"})).To(Succeed())
+		})
+
+		It("pauses the tab switching until it is handed back", func() {
+			Expect(kiosk.IsTakenOver()).To(BeTrue())
+			Expect(kiosk.IsTabSwitching()).To(BeFalse())
+			Expect(kiosk.Status().Takeover).To(Equal("Please leave the building"))
+
+			Expect(kiosk.HandBack()).To(Succeed())
+			Expect(kiosk.IsTakenOver()).To(BeFalse())
+			Expect(kiosk.IsTabSwitching()).To(BeTrue())
+			Expect(kiosk.HandBack()).ToNot(Succeed())
+		})
+
+		It("refuses to switch tabs", func() {
+			Expect(kiosk.NextTab()).To(MatchError(controller.ErrTakenOver))
+			Expect(kiosk.PreviousTab()).To(MatchError(controller.ErrTakenOver))
+			Expect(kiosk.SwitchToTab(kiosk.ImageIDs()[0])).To(MatchError(controller.ErrTakenOver))
+			Expect(kiosk.IsTakenOver()).To(BeTrue())
+		})
+
+		It("hands back to the first of the replaced tabs", func() {
+			Expect(kiosk.ReplaceTabs(tabs("third", "fourth"))).To(Succeed())
+			Expect(kiosk.IsTakenOver()).To(BeTrue())
+
+			Expect(kiosk.HandBack()).To(Succeed())
+			Expect(kiosk.Status().CurrentTab).To(Equal(kiosk.ImageIDs()[0]))
+			Expect(kiosk.IsTabSwitching()).To(BeTrue())
+		})
+	})
+
+	Context("with a tab that fails at first", func() {
+		var server *httptest.Server
+		var requests atomic.Int32
+		var kiosk *controller.Kiosk
+
+		// tab fails to find the heading, which the server only serves from the
+		// second request on
+		tab := func(onError script.ErrorPolicy) *script.Tab {
+			tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: flaky
+  onError: %v
+  script:
+    - go: %v
+    - { wait: "#ready", timeout: 1s }
+`, onError, server.URL)))
+			Expect(err).ToNot(HaveOccurred())
+
+			return tabs[0]
+		}
+
+		BeforeEach(func() {
+			needBrowser()
+
+			requests.Store(0)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) == 1 {
+					fmt.Fprint(w, "<h1>Starting</h1>")
+					return
+				}
+
+				fmt.Fprint(w, `<h1 id="ready">Ready</h1>`)
+			}))
+			DeferCleanup(server.Close)
+
+			kiosk = controller.NewKiosk().
+				WithHeadless(true).
+				WithFlag("no-sandbox", true).
+				WithInterval(time.Hour).
+				WithStatusUpdates(make(chan controller.StatusUpdate))
+			DeferCleanup(kiosk.Close)
+		})
+
+		It("leaves it out of the rotation with skip", func() {
+			Expect(kiosk.NewTab(tab(script.OnErrorSkip))).To(Succeed())
+			Expect(kiosk.ImageIDs()).To(BeEmpty())
+		})
+
+		It("shows a placeholder until it recovers", func() {
+			Expect(kiosk.NewTab(tab(script.OnErrorPlaceholder))).To(Succeed())
+			Expect(kiosk.ImageIDs()).To(HaveLen(1))
+			Eventually(requests.Load, 10*time.Second).Should(BeNumerically(">", 1))
+		})
+
+		It("keeps switching tabs while waiting to retry", func() {
+			created := make(chan error)
+
+			go func() {
+				defer GinkgoRecover()
+				created <- kiosk.NewTab(tab(script.OnErrorRetry))
+			}()
+
+			Eventually(requests.Load).Should(BeNumerically("==", 1))
+			kiosk.StartTabSwitching()
+			Expect(kiosk.IsTabSwitching()).To(BeTrue())
+			Expect(created).ToNot(Receive())
+
+			Eventually(created, 15*time.Second).Should(Receive(BeNil()))
+			Expect(kiosk.ImageIDs()).To(HaveLen(1))
+		})
+
+		It("fails with abort", func() {
+			Expect(kiosk.NewTab(tab(script.OnErrorAbort))).ToNot(Succeed())
+			Expect(kiosk.ImageIDs()).To(BeEmpty())
+		})
+	})
+
+	It("shows media for as long as they take", func() {
+		needBrowser()
+
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "spring.png"), []byte("spring"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "summer.png"), []byte("summer"), 0o600)).To(Succeed())
+
+		server := httptest.NewUnstartedServer(nil)
+		media := controller.NewMediaServer("http://" + server.Listener.Addr().String())
+		server.Config.Handler = media
+		server.Start()
+		DeferCleanup(server.Close)
+
+		kiosk := controller.NewKiosk().
+			WithHeadless(true).
+			WithFlag("no-sandbox", true).
+			WithInterval(100 * time.Millisecond).
+			WithMediaServer(media).
+			WithStatusUpdates(make(chan controller.StatusUpdate))
+		DeferCleanup(kiosk.Close)
+
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: posters
+  folder: { path: %v, duration: 500ms }
+- name: other
+  script:
+    - go: %v/other
+`, dir, server.URL)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kiosk.ReplaceTabs(tabs)).To(Succeed())
+
+		posters := kiosk.ImageIDs()[0]
+		Expect(kiosk.Status().CurrentTab).To(Equal(posters))
+
+		// both files, instead of the interval
+		kiosk.StartTabSwitching()
+		Consistently(func() string { return kiosk.Status().CurrentTab }, 800*time.Millisecond).Should(Equal(posters))
+		Eventually(func() string { return kiosk.Status().CurrentTab }, 2*time.Second).ShouldNot(Equal(posters))
+	})
+
+	Context("attached to a remote browser", func() {
+		var server *httptest.Server
+		var remoteURL string
+		var desktopTab string
+
+		tab := func(name string) *script.Tab {
+			tabs, err := script.Parse([]byte(fmt.Sprintf("- name: %v\n  script:\n    - go: %v/%v\n", name, server.URL, name)))
+			Expect(err).ToNot(HaveOccurred())
+
+			return tabs[0]
+		}
+
+		// pages lists the IDs of the tabs of the remote browser.
+		pages := func() []string {
+			response, err := http.Get(remoteURL + "/json/list")
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+
+			var targets []struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+			}
+			Expect(json.NewDecoder(response.Body).Decode(&targets)).To(Succeed())
+
+			var ids []string
+
+			for _, target := range targets {
+				if target.Type == "page" {
+					ids = append(ids, target.ID)
+				}
+			}
+
+			return ids
+		}
+
+		BeforeEach(func() {
+			needBrowser()
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "<h1>%v</h1>", r.URL.Path)
+			}))
+			DeferCleanup(server.Close)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			port := listener.Addr().(*net.TCPAddr).Port
+			Expect(listener.Close()).To(Succeed())
+
+			// the browser of the desktop session, with a tab of its own
+			desktop := controller.NewKiosk().
+				WithHeadless(true).
+				WithFlag("no-sandbox", true).
+				WithFlag("remote-debugging-port", fmt.Sprint(port))
+			DeferCleanup(desktop.Close)
+
+			Expect(desktop.NewTab(tab("desktop"))).To(Succeed())
+			desktopTab = desktop.ImageIDs()[0]
+			remoteURL = fmt.Sprintf("http://127.0.0.1:%v", port)
+		})
+
+		It("takes over only the tabs it opened before", func() {
+			targetsFile := filepath.Join(GinkgoT().TempDir(), "targets")
+
+			before := controller.NewKiosk().WithRemoteDebuggingURL(remoteURL).WithTargetsFile(targetsFile)
+			Expect(before.NewTab(tab("first"))).To(Succeed())
+			Expect(before.NewTab(tab("second"))).To(Succeed())
+			opened := before.ImageIDs()
+			Expect(opened).ToNot(ContainElement(desktopTab))
+
+			// restarted with fewer tabs
+			after := controller.NewKiosk().WithRemoteDebuggingURL(remoteURL).WithTargetsFile(targetsFile)
+			Expect(after.NewTab(tab("first"))).To(Succeed())
+			after.CloseLeftoverTabs()
+
+			Expect(after.ImageIDs()).To(Equal(opened[:1]))
+			Eventually(pages).Should(ConsistOf(desktopTab, opened[0]))
+		})
+	})
+})
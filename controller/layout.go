@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"uhlig.it/kiosk/script"
+)
+
+//go:embed layout.html.tmpl
+var layoutMarkup string
+
+var layoutTemplate = template.Must(template.New("layout").Parse(layoutMarkup))
+
+const (
+	// paneTimeout limits how long it may take for the page of a pane to get a frame of its own.
+	paneTimeout = 30 * time.Second
+	// healthCheckInterval is how often the health of the panes is checked.
+	healthCheckInterval = time.Minute
+)
+
+// createLayout shows the panes of the tab in frames, and runs the steps of
+// each pane in its frame. The frames of the panes run in processes of their
+// own, as their pages are from other sites than the layout, so they are
+// targets that can be attached to. The caller holds the lock; it is released
+// while the panes are set up.
+func (k *Kiosk) createLayout(ctx context.Context, tab *script.Tab) error {
+	tabID := chromedp.FromContext(ctx).Target.TargetID
+
+	// the panes of a previous attempt stop being maintained
+	if cancel, found := k.layouts[tabID]; found {
+		cancel()
+	}
+
+	panesCtx, cancelPanes := context.WithCancel(ctx)
+	k.layouts[tabID] = cancelPanes
+
+	var buf bytes.Buffer
+	columns, rows := tab.Layout.Grid()
+
+	err := layoutTemplate.Execute(&buf, map[string]any{
+		"name":    tab.Name,
+		"columns": columns,
+		"rows":    rows,
+		"panes":   tab.Layout.Panes,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = chromedp.Run(ctx, append(tab.Setup(), setDocument(buf.String()))...); err != nil {
+		return err
+	}
+
+	// waiting for the frames of the panes may take long, so the kiosk keeps
+	// switching tabs and answering in the meantime
+	errs := make([]error, len(tab.Layout.Panes))
+	var wg sync.WaitGroup
+
+	k.mutex.Unlock()
+
+	for i, pane := range tab.Layout.Panes {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			frame := &paneFrame{layoutCtx: panesCtx, index: i, tab: tab, pane: pane}
+			err := frame.attach()
+
+			if err == nil {
+				err = frame.run(pane.Steps)
+			}
+
+			if err != nil {
+				errs[i] = fmt.Errorf("%v: %w", pane, err)
+				return
+			}
+
+			go frame.maintain()
+		}()
+	}
+
+	wg.Wait()
+	k.mutex.Lock()
+
+	// the tab may have been discarded or created anew in the meantime
+	if panesCtx.Err() != nil {
+		return errors.New("the layout was replaced while its panes were set up")
+	}
+
+	return errors.Join(errs...)
+}
+
+// paneFrame is the frame a pane is shown in. Its target is replaced when the
+// page goes to another site, e.g. when it is redirected to a login page, as
+// the page then runs in another process. The frame is attached to anew then.
+type paneFrame struct {
+	layoutCtx context.Context
+	index     int
+	tab       *script.Tab
+	pane      *script.Pane
+	ctx       context.Context
+	// detached is closed once the target of ctx is gone.
+	detached chan struct{}
+}
+
+// attach attaches to the current target of the frame, and sets up the
+// session of the tab in it.
+func (f *paneFrame) attach() error {
+	if f.ctx != nil {
+		script.EndSession(f.ctx)
+	}
+
+	ctx, err := attachPane(f.layoutCtx, f.index)
+
+	if err != nil {
+		return err
+	}
+
+	if err := chromedp.Run(ctx, f.tab.Session()); err != nil {
+		return err
+	}
+
+	detached := make(chan struct{})
+	sessionID := chromedp.FromContext(ctx).Target.SessionID
+	var once sync.Once
+
+	chromedp.ListenBrowser(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*target.EventDetachedFromTarget); ok && ev.SessionID == sessionID {
+			once.Do(func() { close(detached) })
+		}
+	})
+
+	f.ctx = ctx
+	f.detached = detached
+
+	return nil
+}
+
+// run runs the steps in the frame. A step that the frame went to another site
+// during runs again in the new target, unless it is the 'go' step that got it
+// there.
+func (f *paneFrame) run(steps []script.Step) error {
+	for i := 0; i < len(steps); {
+		stepCtx, cancel := context.WithCancel(f.ctx)
+		detached := f.detached
+
+		// a step waiting for the page of a gone target would wait forever
+		go func() {
+			select {
+			case <-detached:
+				cancel()
+			case <-stepCtx.Done():
+			}
+		}()
+
+		err := chromedp.Run(stepCtx, steps[i].Action())
+		cancel()
+
+		if isClosed(f.detached) {
+			if attachErr := f.attach(); attachErr != nil {
+				return fmt.Errorf("went to another site, but could not be found there: %w", attachErr)
+			}
+
+			if _, navigated := steps[i].(script.Go); err != nil && !navigated {
+				continue
+			}
+		} else if err != nil {
+			return err
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+// attachPane returns a context for the frame of the pane with the given index.
+func attachPane(ctx context.Context, index int) (context.Context, error) {
+	var nodes []*cdp.Node
+
+	err := chromedp.Run(ctx, chromedp.Nodes(fmt.Sprintf("#pane-%d", index), &nodes, chromedp.ByID))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the ID of a frame running in a process of its own is the one of its target
+	frameID := target.ID(nodes[0].FrameID)
+
+	waitCtx, cancel := context.WithTimeout(ctx, paneTimeout)
+	defer cancel()
+
+	for {
+		targets, err := chromedp.Targets(waitCtx)
+
+		if err != nil {
+			return nil, fmt.Errorf("the page is not shown in a frame of its own, which needs site isolation: %w", err)
+		}
+
+		for _, info := range targets {
+			if info.TargetID == frameID {
+				// the pane is detached when ctx is done
+				paneCtx, _ := chromedp.NewContext(ctx, chromedp.WithTargetID(frameID))
+				return paneCtx, nil
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return nil, errors.New("the page is not shown in a frame of its own, which needs site isolation")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// maintain reloads the page of the pane as often as it asks for, and runs
+// its steps again if it is not healthy, until the layout is gone. When the
+// page goes to another site, the frame is attached to anew.
+func (f *paneFrame) maintain() {
+	defer func() { script.EndSession(f.ctx) }()
+
+	var reload, check <-chan time.Time
+
+	if f.pane.Reload > 0 {
+		ticker := time.NewTicker(f.pane.Reload)
+		defer ticker.Stop()
+		reload = ticker.C
+	}
+
+	if f.pane.Health != nil {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+
+	for {
+		select {
+		case <-f.layoutCtx.Done():
+			return
+		case <-f.detached:
+			if err := f.attach(); err != nil {
+				log.Printf("%v of tab '%v' went to another site, but could not be found there: %v", f.pane, f.tab.Name, err)
+				return
+			}
+		case <-reload:
+			if err := chromedp.Run(f.ctx, chromedp.Reload()); err != nil {
+				log.Printf("could not reload %v of tab '%v': %v", f.pane, f.tab.Name, err)
+			}
+		case <-check:
+			var healthy bool
+
+			err := chromedp.Run(f.ctx, chromedp.ActionFunc(func(ctx context.Context) (err error) {
+				healthy, err = f.pane.Health.Check(ctx)
+				return err
+			}))
+
+			if err == nil && healthy {
+				continue
+			}
+
+			log.Printf("%v of tab '%v' is not healthy; running its steps again", f.pane, f.tab.Name)
+
+			if err := f.run(f.pane.Steps); err != nil {
+				log.Printf("could not run the steps of %v of tab '%v': %v", f.pane, f.tab.Name, err)
+			}
+		}
+	}
+}
+
+// setDocument replaces the content of the page with the given markup.
+func setDocument(markup string) chromedp.Action {
+	return chromedp.Tasks{
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			frameTree, err := page.GetFrameTree().Do(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			return page.SetDocumentContent(frameTree.Frame.ID, markup).Do(ctx)
+		}),
+	}
+}
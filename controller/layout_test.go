@@ -0,0 +1,66 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Layout", func() {
+	var dashboard, login *httptest.Server
+	var dashboardURL, loginURL string
+
+	BeforeEach(func() {
+		needBrowser()
+
+		dashboard = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/start" {
+				http.Redirect(w, r, loginURL+"/login", http.StatusFound)
+				return
+			}
+
+			fmt.Fprint(w, `<h1>Dashboard</h1>`)
+		}))
+		DeferCleanup(dashboard.Close)
+
+		login = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `<a href="%v/dashboard">Sign in</a>`, dashboardURL)
+		}))
+		DeferCleanup(login.Close)
+
+		// 127.0.0.1 and localhost are different sites, so their pages run in different processes
+		dashboardURL = dashboard.URL
+		loginURL = strings.Replace(login.URL, "127.0.0.1", "localhost", 1)
+	})
+
+	It("follows a pane to another site", func() {
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: NOC
+  layout:
+    panes:
+      - name: dashboard
+        script:
+          - go: %v/start
+          - wait: text:Sign in
+            timeout: 5s
+          - click: text:Sign in
+          - wait: text:Dashboard
+            timeout: 5s
+`, dashboardURL)))
+		Expect(err).ToNot(HaveOccurred())
+
+		kiosk := controller.NewKiosk().
+			WithHeadless(true).
+			WithFlag("no-sandbox", true).
+			WithStatusUpdates(make(chan controller.StatusUpdate))
+		DeferCleanup(kiosk.Close)
+
+		Expect(kiosk.NewTab(tabs[0])).To(Succeed())
+	})
+})
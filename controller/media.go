@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"uhlig.it/kiosk/script"
+)
+
+//go:embed media.html.tmpl
+var mediaMarkup string
+
+var mediaTemplate = template.Must(template.New("media").Parse(mediaMarkup))
+
+// defaultMediaDuration is how long a file of a folder or a page of a PDF is shown by default.
+const defaultMediaDuration = 10 * time.Second
+
+var (
+	imageExtensions = []string{".apng", ".avif", ".gif", ".jpeg", ".jpg", ".png", ".svg", ".webp"}
+	videoExtensions = []string{".m4v", ".mp4", ".ogv", ".webm"}
+	// pdfPage matches the objects of the pages of a PDF, but not the ones listing them.
+	pdfPage = regexp.MustCompile(`/Type\s*/Page[^s]`)
+)
+
+// MediaServer serves the files of media tabs, and the pages showing them,
+// so that the browser shows local files like any other page.
+type MediaServer struct {
+	baseURL string
+	mutex   sync.RWMutex
+	media   map[string]*script.Media
+}
+
+// NewMediaServer creates a server that is reachable by the browser at
+// baseURL, e.g. http://localhost:8011. It handles the paths below /media/.
+func NewMediaServer(baseURL string) *MediaServer {
+	return &MediaServer{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		media:   make(map[string]*script.Media),
+	}
+}
+
+// Add serves the media and returns the URL of the page showing it.
+func (s *MediaServer) Add(media *script.Media) (string, error) {
+	if err := media.Lint(); err != nil {
+		return "", err
+	}
+
+	if media.Kind == script.MediaPDF {
+		if _, err := countPages(media.File()); err != nil {
+			return "", err
+		}
+	}
+
+	// the same media gets the same URL, e.g. when the tabs are replaced
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v\x00%v\x00%v", media.Kind, media.File(), media.Duration)))
+	id := hex.EncodeToString(hash[:8])
+
+	s.mutex.Lock()
+	s.media[id] = media
+	s.mutex.Unlock()
+
+	return fmt.Sprintf("%v/media/%v/", s.baseURL, id), nil
+}
+
+func (s *MediaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/media/"), "/")
+
+	s.mutex.RLock()
+	media, found := s.media[id]
+	s.mutex.RUnlock()
+
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case rest == "":
+		s.servePage(w, media)
+	case rest == "file" && media.Kind != script.MediaFolder:
+		http.ServeFile(w, r, media.File())
+	case rest == "files" && media.Kind == script.MediaFolder:
+		serveFolder(w, media)
+	case strings.HasPrefix(rest, "files/") && media.Kind == script.MediaFolder:
+		serveFolderFile(w, r, media, strings.TrimPrefix(rest, "files/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *MediaServer) servePage(w http.ResponseWriter, media *script.Media) {
+	pages := 1
+
+	if media.Kind == script.MediaPDF {
+		var err error
+
+		if pages, err = countPages(media.File()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	err := mediaTemplate.Execute(w, map[string]any{
+		"kind":     string(media.Kind),
+		"path":     media.Path,
+		"duration": partDuration(media).Milliseconds(),
+		"pages":    pages,
+	})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// mediaFile is a file of a folder that can be shown.
+type mediaFile struct {
+	Name  string `json:"name"`
+	Video bool   `json:"video,omitempty"`
+}
+
+// serveFolder lists the images and videos of the folder by name.
+func serveFolder(w http.ResponseWriter, media *script.Media) {
+	files, err := folderFiles(media)
+
+	if err != nil {
+		http.Error(w, `{"error": "could not read the folder"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// folderFiles lists the images and videos of the folder.
+func folderFiles(media *script.Media) ([]mediaFile, error) {
+	entries, err := os.ReadDir(media.File())
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []mediaFile{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isMediaName(entry.Name()) {
+			continue
+		}
+
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		files = append(files, mediaFile{Name: entry.Name(), Video: slices.Contains(videoExtensions, extension)})
+	}
+
+	return files, nil
+}
+
+// serveFolderFile serves a file of the folder, but only one that
+// serveFolder lists, so neither hidden files, other files nor
+// subfolders are reachable.
+func serveFolderFile(w http.ResponseWriter, r *http.Request, media *script.Media, name string) {
+	if strings.ContainsAny(name, `/\`) || !isMediaName(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := filepath.Join(media.File(), name)
+
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeFile(w, r, path)
+}
+
+// isMediaName tells whether the name of a file of a folder is one of an
+// image or a video, which is not hidden.
+func isMediaName(name string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+
+	return slices.Contains(imageExtensions, extension) || slices.Contains(videoExtensions, extension)
+}
+
+// countPages tells how many pages the PDF has. They cannot be counted e.g.
+// if they are compressed.
+func countPages(path string) (int, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	pages := len(pdfPage.FindAllIndex(content, -1))
+
+	if pages == 0 {
+		return 0, fmt.Errorf("could not count the pages of '%v'; are they compressed?", path)
+	}
+
+	return pages, nil
+}
+
+// partDuration is how long a file of a folder or a page of a PDF is shown.
+func partDuration(media *script.Media) time.Duration {
+	if media.Duration == 0 {
+		return defaultMediaDuration
+	}
+
+	return media.Duration
+}
+
+// showTime tells how long it takes to show the media once: the duration of a
+// single image or video, all pages of a PDF, or all files of a folder. It is
+// 0 if that is up to the interval of the kiosk, e.g. for an empty folder.
+func showTime(media *script.Media) (time.Duration, error) {
+	switch media.Kind {
+	case script.MediaPDF:
+		pages, err := countPages(media.File())
+
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(pages) * partDuration(media), nil
+	case script.MediaFolder:
+		// videos play until they end, which is taken to be the duration, too
+		files, err := folderFiles(media)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(len(files)) * partDuration(media), nil
+	default:
+		return media.Duration, nil
+	}
+}
+
+// showMedia opens the page of the media tab.
+func (k *Kiosk) showMedia(ctx context.Context, tab *script.Tab) error {
+	if k.mediaServer == nil {
+		return errors.New("media tabs need the HTTP server of the kiosk")
+	}
+
+	url, err := k.mediaServer.Add(tab.Media)
+
+	if err != nil {
+		return err
+	}
+
+	return chromedp.Run(ctx, append(tab.Setup(), chromedp.Navigate(url))...)
+}
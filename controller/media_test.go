@@ -0,0 +1,132 @@
+package controller_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("MediaServer", func() {
+	var dir string
+	var server *httptest.Server
+	var media *controller.MediaServer
+
+	get := func(url string) (int, string) {
+		response, err := http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return response.StatusCode, string(body)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "spring.png"), []byte("spring"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "loop.mp4"), []byte("loop"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "deck.pdf"), []byte("<< /Type /Pages /Count 2 >> << /Type /Page >> << /Type/Page >>"), 0o600)).To(Succeed())
+
+		server = httptest.NewUnstartedServer(nil)
+		media = controller.NewMediaServer("http://" + server.Listener.Addr().String())
+		server.Config.Handler = media
+		server.Start()
+		DeferCleanup(server.Close)
+	})
+
+	It("serves an image and the page showing it", func() {
+		url, err := media.Add(&script.Media{Kind: script.MediaImage, Path: filepath.Join(dir, "spring.png")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(url).To(HavePrefix(server.URL + "/media/"))
+
+		status, page := get(url)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(page).To(ContainSubstring(`<img src="file"`))
+
+		status, file := get(url + "file")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(file).To(Equal("spring"))
+	})
+
+	It("gives the same media the same URL", func() {
+		first, err := media.Add(&script.Media{Kind: script.MediaVideo, Path: filepath.Join(dir, "loop.mp4")})
+		Expect(err).ToNot(HaveOccurred())
+
+		second, err := media.Add(&script.Media{Kind: script.MediaVideo, Path: filepath.Join(dir, "loop.mp4")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(Equal(first))
+	})
+
+	It("counts the pages of a PDF", func() {
+		url, err := media.Add(&script.Media{Kind: script.MediaPDF, Path: filepath.Join(dir, "deck.pdf")})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, page := get(url)
+		Expect(page).To(MatchRegexp(`page % +2 +\+ 1`))
+	})
+
+	It("rejects a PDF whose pages cannot be counted", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "compressed.pdf"), []byte("<< /Type /ObjStm /Filter /FlateDecode >>"), 0o600)).To(Succeed())
+
+		_, err := media.Add(&script.Media{Kind: script.MediaPDF, Path: filepath.Join(dir, "compressed.pdf")})
+		Expect(err).To(MatchError(ContainSubstring("could not count the pages")))
+	})
+
+	It("lists the images and videos of a folder", func() {
+		url, err := media.Add(&script.Media{Kind: script.MediaFolder, Path: dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, files := get(url + "files")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(files).To(MatchJSON(`[{"name": "loop.mp4", "video": true}, {"name": "spring.png"}]`))
+
+		status, file := get(url + "files/spring.png")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(file).To(Equal("spring"))
+	})
+
+	It("does not serve files outside of a folder", func() {
+		url, err := media.Add(&script.Media{Kind: script.MediaFolder, Path: dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, _ := get(url + "files/../../../etc/passwd")
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+
+	It("serves only the files of a folder it lists", func() {
+		Expect(os.WriteFile(filepath.Join(dir, ".hidden.png"), []byte("hidden"), 0o600)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "album.png"), 0o700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "album.png", "autumn.png"), []byte("autumn"), 0o600)).To(Succeed())
+
+		url, err := media.Add(&script.Media{Kind: script.MediaFolder, Path: dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, name := range []string{"notes.txt", "deck.pdf", ".hidden.png", "album.png", "album.png/autumn.png", "album.png%2Fautumn.png", "summer.png", ""} {
+			status, _ := get(url + "files/" + name)
+			Expect(status).To(Equal(http.StatusNotFound), name)
+		}
+
+		status, file := get(url + "files/loop.mp4")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(file).To(Equal("loop"))
+	})
+
+	It("rejects missing files", func() {
+		_, err := media.Add(&script.Media{Kind: script.MediaImage, Path: filepath.Join(dir, "summer.png")})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not know other media", func() {
+		status, _ := get(server.URL + "/media/0123456789abcdef/")
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"html/template"
+
+	"github.com/chromedp/chromedp"
+	"uhlig.it/kiosk/script"
+)
+
+//go:embed placeholder.html.tmpl
+var placeholderMarkup string
+
+var placeholderTemplate = template.Must(template.New("placeholder").Parse(placeholderMarkup))
+
+// showPlaceholder replaces the content of the tab with a page describing why it failed.
+func (k *Kiosk) showPlaceholder(ctx context.Context, tab *script.Tab, cause error) error {
+	var buf bytes.Buffer
+
+	err := placeholderTemplate.Execute(&buf, map[string]any{
+		"name":  tab.Name,
+		"url":   tab.URL(),
+		"error": cause.Error(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = chromedp.Run(ctx, setDocument(buf.String())); err != nil {
+		return err
+	}
+
+	return k.saveScreenshot(ctx, chromedp.FromContext(ctx).Target.TargetID)
+}
@@ -0,0 +1,68 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Rules", func() {
+	var server *httptest.Server
+	var trial *controller.Trial
+
+	BeforeEach(func() {
+		needBrowser()
+		trial = newTrial()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+  <body>
+    <script src="/ads.js"></script>
+    <script src="/cdn/greeting.js"></script>
+  </body>
+</html>`)
+		})
+		mux.HandleFunc("/ads.js", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `document.body.insertAdjacentHTML('beforeend', '<p>Advertisement</p>')`)
+		})
+		mux.HandleFunc("/mirror/greeting.js", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `document.body.insertAdjacentHTML('beforeend', '<p>Hello from the mirror</p>')`)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	It("blocks and rewrites requests", func() {
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Lobby
+  block:
+    - "*/ads.js"
+  rewrite:
+    - from: %[1]v/cdn/
+      to: %[1]v/mirror/
+  script:
+    - go: %[1]v
+    - wait: text:Hello from the mirror
+      timeout: 3s
+    - if:
+        text: Advertisement
+      then:
+        - wait: css:#never
+          timeout: 1ms
+`, server.URL)))
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := trial.Run(tabs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Error).To(BeEmpty())
+		Expect(tabs[0].Block[0].Hits()).To(BeEquivalentTo(1))
+		Expect(tabs[0].Rewrite[0].Hits()).To(BeEquivalentTo(1))
+	})
+})
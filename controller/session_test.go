@@ -0,0 +1,70 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Session", func() {
+	var dashboard, cdn *httptest.Server
+	var trial *controller.Trial
+	var mutex sync.Mutex
+	var received map[string]string
+
+	// record remembers the header X-Kiosk of each request by its path.
+	record := func(r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		received[r.URL.Path] = r.Header.Get("X-Kiosk")
+	}
+
+	BeforeEach(func() {
+		needBrowser()
+		trial = newTrial()
+
+		received = make(map[string]string)
+
+		cdn = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			fmt.Fprint(w, `document.body.insertAdjacentHTML('beforeend', '<p>Hello from the CDN</p>')`)
+		}))
+		DeferCleanup(cdn.Close)
+
+		dashboard = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			fmt.Fprintf(w, `<!doctype html><html><body><script src="%v/greeting.js"></script></body></html>`, cdn.URL)
+		}))
+		DeferCleanup(dashboard.Close)
+	})
+
+	It("sends the headers to the origin of the tab only", func() {
+		// the servers differ by port, which makes them different origins
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Dashboard
+  headers:
+    X-Kiosk: lobby
+  script:
+    - go: %v/dashboard
+    - wait: text:Hello from the CDN
+      timeout: 3s
+`, dashboard.URL)))
+		Expect(err).ToNot(HaveOccurred())
+
+		report, err := trial.Run(tabs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Error).To(BeEmpty())
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(received).To(HaveKeyWithValue("/dashboard", "lobby"))
+		Expect(received).To(HaveKeyWithValue("/greeting.js", ""))
+	})
+})
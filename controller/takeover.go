@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"html/template"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+//go:embed takeover.html.tmpl
+var takeoverMarkup string
+
+var takeoverTemplate = template.Must(template.New("takeover").Parse(takeoverMarkup))
+
+// Takeover is what a kiosk shows instead of its tabs, e.g. during an
+// incident: either the page at URL, or the message.
+type Takeover struct {
+	URL     string
+	Message string
+}
+
+func (t Takeover) String() string {
+	if t.URL != "" {
+		return t.URL
+	}
+
+	return t.Message
+}
+
+// ErrTakenOver is returned when switching tabs during a takeover, which stays
+// until it is handed back.
+var ErrTakenOver = errors.New("the kiosk is taken over")
+
+// takenOver is what the kiosk returns to when it is handed back.
+type takenOver struct {
+	Takeover
+	ctx             context.Context
+	previousTab     target.ID
+	wasTabSwitching bool
+}
+
+// TakeOver pauses the tab switching and shows the takeover in a tab of its
+// own, which is not part of the rotation. Taking over again replaces what the
+// tab shows, but keeps what HandBack returns to.
+func (k *Kiosk) TakeOver(takeover Takeover) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.takeover == nil {
+		wasTabSwitching := k.isTabSwitching()
+
+		if wasTabSwitching {
+			k.pauseTabSwitching()
+		}
+
+		k.takeover = &takenOver{
+			ctx:             k.newTabContext(),
+			previousTab:     k.currentTab,
+			wasTabSwitching: wasTabSwitching,
+		}
+	}
+
+	k.takeover.Takeover = takeover
+	action := chromedp.Navigate(takeover.URL)
+
+	if takeover.URL == "" {
+		var buf bytes.Buffer
+
+		if err := takeoverTemplate.Execute(&buf, map[string]any{"message": takeover.Message}); err != nil {
+			return err
+		}
+
+		action = setDocument(buf.String())
+	}
+
+	err := chromedp.Run(k.takeover.ctx, action)
+	k.recordTargets()
+
+	if err != nil {
+		return err
+	}
+
+	return k.activateTakeover()
+}
+
+// activateTakeover brings the tab of the takeover to the front again, e.g.
+// after new tabs were opened above it.
+func (k *Kiosk) activateTakeover() error {
+	if k.takeover == nil {
+		return nil
+	}
+
+	targetID := chromedp.FromContext(k.takeover.ctx).Target.TargetID
+
+	return chromedp.Run(k.rootContext(), target.ActivateTarget(targetID))
+}
+
+// HandBack closes the tab of the takeover, and returns to the tab shown
+// before, or the first one if it was replaced meanwhile. Tab switching is
+// resumed if it was going on before, or was resumed during the takeover.
+func (k *Kiosk) HandBack() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.takeover == nil {
+		return errors.New("the kiosk is not taken over")
+	}
+
+	taken := k.takeover
+	k.takeover = nil
+
+	var err error
+	ctx, findErr := k.findTab(taken.previousTab)
+
+	if findErr != nil && len(k.allContexts) > 0 {
+		ctx = k.allContexts[0]
+	}
+
+	// switching first, so that the browser does not pick a tab on its own
+	if ctx != nil {
+		err = k.switchToTab(ctx)
+	}
+
+	k.discardTabContext(taken.ctx)
+	k.recordTargets()
+
+	if taken.wasTabSwitching {
+		k.startTabSwitching()
+	}
+
+	return err
+}
+
+// IsTakenOver tells whether the kiosk shows a takeover.
+func (k *Kiosk) IsTakenOver() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return k.takeover != nil
+}
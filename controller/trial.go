@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"uhlig.it/kiosk/script"
+)
+
+// Trial runs the steps of a single tab in a headless browser and reports on each of them.
+type Trial struct {
+	timeout    time.Duration
+	extraFlags map[string]interface{}
+}
+
+// Report describes how the steps of a tab went during a Trial.
+type Report struct {
+	Tab      string           `json:"tab"`
+	Started  time.Time        `json:"started"`
+	Duration time.Duration    `json:"duration"`
+	Steps    []*StepReport    `json:"steps"`
+	Console  []ConsoleMessage `json:"console"`
+	// FailedStep is the number of the step that failed, starting at 1, or 0 if no step failed.
+	FailedStep int    `json:"failedStep,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type StepReport struct {
+	Description string        `json:"description"`
+	Duration    time.Duration `json:"duration"`
+	Screenshot  []byte        `json:"screenshot,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+type ConsoleMessage struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+	Text  string    `json:"text"`
+}
+
+func NewTrial() *Trial {
+	return &Trial{
+		timeout:    time.Minute,
+		extraFlags: make(map[string]interface{}),
+	}
+}
+
+// WithTimeout limits how long all steps of the tab may take together.
+func (t *Trial) WithTimeout(timeout time.Duration) *Trial {
+	t.timeout = timeout
+	return t
+}
+
+func (t *Trial) WithFlag(key string, value interface{}) *Trial {
+	t.extraFlags[key] = value
+	return t
+}
+
+// Run performs the steps of the tab one after another, taking a screenshot
+// after each of them, and stops at the first one that fails. The returned
+// error is only about the browser; failing steps are recorded in the report.
+func (t *Trial) Run(tab *script.Tab) (*Report, error) {
+	allocatorOptions := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+	)
+
+	for key, value := range t.extraFlags {
+		allocatorOptions = append(allocatorOptions, chromedp.Flag(key, value))
+	}
+
+	allocCtx, cancelAllocator := chromedp.NewExecAllocator(context.Background(), allocatorOptions...)
+	defer cancelAllocator()
+
+	ctx, cancelContext := chromedp.NewContext(allocCtx)
+	defer cancelContext()
+
+	report := &Report{
+		Tab:     tab.Name,
+		Started: time.Now(),
+	}
+
+	var mutex sync.Mutex
+	var console []ConsoleMessage
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		var message ConsoleMessage
+
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			var args []string
+
+			for _, arg := range ev.Args {
+				args = append(args, describeRemoteObject(arg))
+			}
+
+			message = ConsoleMessage{Level: ev.Type.String(), Text: strings.Join(args, " ")}
+		case *runtime.EventExceptionThrown:
+			message = ConsoleMessage{Level: "exception", Text: ev.ExceptionDetails.Error()}
+		default:
+			return
+		}
+
+		message.Time = time.Now()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		console = append(console, message)
+	})
+
+	// starts the browser
+	if err := chromedp.Run(ctx); err != nil {
+		return nil, fmt.Errorf("could not start the browser: %w", err)
+	}
+
+	stepsCtx, cancelSteps := context.WithTimeout(ctx, t.timeout)
+	defer cancelSteps()
+
+	if err := chromedp.Run(stepsCtx, tab.Setup()...); err != nil {
+		report.Error = fmt.Sprintf("could not apply the settings of the tab: %v", err)
+	}
+
+	steps := tab.Steps
+
+	// the panes of a layout are tried one after another
+	if tab.Layout != nil {
+		steps = tab.Layout.Steps()
+	}
+
+	if (tab.Media != nil || tab.Announcement != nil) && report.Error == "" {
+		url, err := serveLocally(stepsCtx, tab)
+
+		if err != nil {
+			report.Error = fmt.Sprintf("could not serve the tab: %v", err)
+		}
+
+		steps = []script.Step{script.Go(url)}
+	}
+
+	for i, step := range steps {
+		if report.Error != "" {
+			break
+		}
+
+		stepReport := &StepReport{Description: step.String()}
+		report.Steps = append(report.Steps, stepReport)
+
+		started := time.Now()
+		err := chromedp.Run(stepsCtx, step.Action())
+		stepReport.Duration = time.Since(started)
+
+		if err != nil {
+			stepReport.Error = err.Error()
+			report.FailedStep = i + 1
+			report.Error = fmt.Sprintf("step %v failed: %v", i+1, err)
+		}
+
+		// the screenshot of a failed step is taken regardless of the timeout, as it shows what went wrong
+		if shotErr := chromedp.Run(ctx, chromedp.CaptureScreenshot(&stepReport.Screenshot)); shotErr != nil && err == nil {
+			stepReport.Error = fmt.Sprintf("could not take screenshot: %v", shotErr)
+		}
+	}
+
+	report.Duration = time.Since(report.Started)
+
+	// closing the browser delivers the console messages still underway
+	cancelContext()
+
+	mutex.Lock()
+	report.Console = slices.Clone(console)
+	mutex.Unlock()
+
+	return report, nil
+}
+
+// serveLocally serves the media or the announcement of the tab on a local
+// port until ctx is done, and returns the URL of the page showing it.
+func serveLocally(ctx context.Context, tab *script.Tab) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := "http://" + listener.Addr().String()
+	mediaServer := NewMediaServer(baseURL)
+	// edits made on the kiosk are not tried
+	announcementServer := NewAnnouncementServer(baseURL, "")
+
+	mux := http.NewServeMux()
+	mux.Handle("/media/", mediaServer)
+	mux.Handle("/announcements/", announcementServer)
+
+	go http.Serve(listener, mux)
+	context.AfterFunc(ctx, func() { listener.Close() })
+
+	if tab.Announcement != nil {
+		return announcementServer.Add(tab.Name, tab.Announcement)
+	}
+
+	return mediaServer.Add(tab.Media)
+}
+
+// Succeeded tells whether all steps succeeded.
+func (r *Report) Succeeded() bool {
+	return r.Error == ""
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+//go:embed report.html.tmpl
+var reportMarkup string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"png": func(b []byte) template.URL {
+		return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b))
+	},
+}).Parse(reportMarkup))
+
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+func describeRemoteObject(o *runtime.RemoteObject) string {
+	if o.Value != nil {
+		var s string
+
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+
+		return string(o.Value)
+	}
+
+	if o.Description != "" {
+		return o.Description
+	}
+
+	return o.Type.String()
+}
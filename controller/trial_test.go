@@ -0,0 +1,155 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Trial", func() {
+	var server *httptest.Server
+	var trial *controller.Trial
+
+	parse := func(steps string) *script.Tab {
+		tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Trial
+  script:
+%v
+`, steps)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+
+		return tabs[0]
+	}
+
+	BeforeEach(func() {
+		needBrowser()
+		trial = newTrial()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+  <body>
+    <input id="name">
+    <button onclick="console.log('clicked', document.getElementById('name').value)">Greet</button>
+    <script>console.warn('loaded')</script>
+  </body>
+</html>`)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	Context("all steps succeed", func() {
+		var report *controller.Report
+
+		BeforeEach(func() {
+			var err error
+			report, err = trial.Run(parse(fmt.Sprintf(`
+    - go: %v
+    - type: { selector: "css:#name", value: Jane }
+    - click: text:Greet
+`, server.URL)))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("succeeds", func() {
+			Expect(report.Succeeded()).To(BeTrue())
+			Expect(report.Error).To(BeEmpty())
+		})
+
+		It("reports every step with a screenshot", func() {
+			Expect(report.Steps).To(HaveLen(3))
+
+			for _, step := range report.Steps {
+				Expect(step.Error).To(BeEmpty())
+				Expect(step.Screenshot).ToNot(BeEmpty())
+			}
+		})
+
+		It("records console messages", func() {
+			Expect(report.Console).To(ContainElements(
+				HaveField("Text", "loaded"),
+				HaveField("Text", "clicked Jane"),
+			))
+		})
+	})
+
+	Context("a step fails", func() {
+		var report *controller.Report
+
+		BeforeEach(func() {
+			var err error
+			report, err = trial.Run(parse(fmt.Sprintf(`
+    - go: %v
+    - { wait: "css:#missing", timeout: 500ms }
+    - click: text:Greet
+`, server.URL)))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("names the failing step", func() {
+			Expect(report.Succeeded()).To(BeFalse())
+			Expect(report.FailedStep).To(Equal(2))
+			Expect(report.Error).To(HavePrefix("step 2 failed: "))
+		})
+
+		It("stops at the failing step", func() {
+			Expect(report.Steps).To(HaveLen(2))
+			Expect(report.Steps[1].Error).ToNot(BeEmpty())
+			Expect(report.Steps[1].Screenshot).ToNot(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("Report", func() {
+	var report *controller.Report
+
+	BeforeEach(func() {
+		report = &controller.Report{
+			Tab:      "Dashboard",
+			Started:  time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC),
+			Duration: 3 * time.Second,
+			Steps: []*controller.StepReport{
+				{Description: "go to https://example.com", Duration: time.Second, Screenshot: []byte{0x89, 'P', 'N', 'G'}},
+				{Description: "click the element addressed by '//a'", Duration: 2 * time.Second, Error: "context deadline exceeded"},
+			},
+			Console: []controller.ConsoleMessage{
+				{Level: "error", Text: "<script>alert(1)</script>"},
+			},
+			FailedStep: 2,
+			Error:      "step 2 failed: context deadline exceeded",
+		}
+	})
+
+	It("writes JSON", func() {
+		var buf bytes.Buffer
+		Expect(report.WriteJSON(&buf)).To(Succeed())
+
+		var decoded map[string]any
+		Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("tab", "Dashboard"))
+		Expect(decoded).To(HaveKeyWithValue("failedStep", BeNumerically("==", 2)))
+		Expect(decoded).To(HaveKeyWithValue("steps", HaveLen(2)))
+	})
+
+	It("writes HTML", func() {
+		var buf bytes.Buffer
+		Expect(report.WriteHTML(&buf)).To(Succeed())
+
+		html := buf.String()
+		Expect(html).To(ContainSubstring("<title>Trial of Dashboard</title>"))
+		Expect(html).To(ContainSubstring(`<img src="data:image/png;base64,iVBORw=="`))
+		Expect(html).To(ContainSubstring("step 2 failed: context deadline exceeded"))
+		Expect(html).To(ContainSubstring("&lt;script&gt;alert(1)&lt;/script&gt;"))
+	})
+})
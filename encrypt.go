@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"uhlig.it/kiosk/script"
+)
+
+type encryptCommand struct {
+	KeyFile string `short:"k" long:"key-file" description:"file holding the key; 32 raw or 64 hex-encoded bytes" required:"yes"`
+}
+
+// Execute encrypts the secret read from STDIN, so that it does not end up in
+// the shell history, and prints the content of the file for an encrypted
+// secret. A trailing newline, e.g. of echo, is not part of the secret.
+func (c *encryptCommand) Execute(args []string) error {
+	key, err := os.ReadFile(c.KeyFile)
+
+	if err != nil {
+		return fmt.Errorf("could not read key file: %w", err)
+	}
+
+	secret, err := io.ReadAll(os.Stdin)
+
+	if err != nil {
+		return fmt.Errorf("could not read secret: %w", err)
+	}
+
+	encrypted, err := script.EncryptSecret(key, strings.TrimSuffix(strings.TrimSuffix(string(secret), "\n"), "\r"))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(encrypted)
+	return err
+}
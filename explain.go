@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"uhlig.it/kiosk/script"
+)
+
+type explainCommand struct {
+	Format string `short:"f" long:"format" description:"how to print the plan" choice:"table" choice:"json" default:"table"`
+	Args   struct {
+		Scriptfile string `description:"script to explain; reads from STDIN if not given"`
+	} `positional-args:"yes"`
+}
+
+type explainedTab struct {
+	Screen  string   `json:"screen,omitempty"`
+	Name    string   `json:"name"`
+	OnError string   `json:"onError"`
+	URL     string   `json:"url"`
+	Steps   []string `json:"steps"`
+}
+
+// Execute prints the tabs and steps of a script after variables, includes
+// and snippets are resolved, without starting the browser.
+func (c *explainCommand) Execute(args []string) error {
+	scriptBytes, err := readScript(c.Args.Scriptfile)
+
+	if err != nil {
+		return fmt.Errorf("could not read scriptfile: %w", err)
+	}
+
+	parser, err := newScriptParser(c.Args.Scriptfile)
+
+	if err != nil {
+		return err
+	}
+
+	screens, err := parser.ParseScreens(scriptBytes)
+
+	if err != nil {
+		return err
+	}
+
+	plan := []explainedTab{}
+
+	for _, screen := range screens {
+		for _, tab := range screen.Tabs {
+			onError := tab.OnError
+
+			if onError == "" {
+				onError = script.OnErrorAbort
+			}
+
+			steps := tab.Steps
+
+			if tab.Layout != nil {
+				steps = tab.Layout.Steps()
+			}
+
+			explained := explainedTab{
+				Screen:  screen.Name,
+				Name:    tab.Name,
+				OnError: string(onError),
+				URL:     tab.URL(),
+				Steps:   make([]string, 0, len(steps)),
+			}
+
+			for _, step := range steps {
+				explained.Steps = append(explained.Steps, step.String())
+			}
+
+			if tab.Media != nil {
+				explained.Steps = append(explained.Steps, fmt.Sprintf("show %v", tab.Media))
+			}
+
+			if tab.Announcement != nil {
+				explained.Steps = append(explained.Steps, fmt.Sprintf("show %v", tab.Announcement))
+			}
+
+			plan = append(plan, explained)
+		}
+	}
+
+	if c.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCREEN\tTAB\tON ERROR\tSTEP\tDESCRIPTION")
+
+	for _, tab := range plan {
+		for i, step := range tab.Steps {
+			if i == 0 {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", tab.Screen, tab.Name, tab.OnError, i+1, step)
+			} else {
+				fmt.Fprintf(w, "\t\t\t%v\t%v\n", i+1, step)
+			}
+		}
+
+		if len(tab.Steps) == 0 {
+			fmt.Fprintf(w, "%v\t%v\t%v\t\t%v\n", tab.Screen, tab.Name, tab.OnError, "do nothing")
+		}
+	}
+
+	return w.Flush()
+}
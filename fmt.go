@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"uhlig.it/kiosk/script"
+)
+
+type fmtCommand struct {
+	Write bool `short:"w" long:"write" description:"rewrite the scripts in place instead of printing them"`
+	Args  struct {
+		Scriptfiles []string `description:"scripts to format; reads from STDIN if none are given"`
+	} `positional-args:"yes"`
+}
+
+// Execute prints scripts in canonical YAML, with variables, includes and
+// snippets resolved. Secrets are written as they were referenced. Scripts
+// using variables, includes or snippets are not rewritten, as that would
+// replace them by what they resolved to on this machine.
+func (c *fmtCommand) Execute(args []string) error {
+	paths := c.Args.Scriptfiles
+
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	for _, path := range paths {
+		scriptBytes, err := readScript(path)
+
+		if err != nil {
+			return fmt.Errorf("could not read script: %w", err)
+		}
+
+		parser, err := newScriptParser(path)
+
+		if err != nil {
+			return err
+		}
+
+		screens, err := parser.ParseScreens(scriptBytes)
+
+		if err != nil {
+			return err
+		}
+
+		formatted, err := script.MarshalScreens(screens)
+
+		if err != nil {
+			return fmt.Errorf("could not format %v: %w", path, err)
+		}
+
+		if !c.Write || path == "" || path == "-" {
+			os.Stdout.Write(formatted)
+			continue
+		}
+
+		if script.IsURL(path) || script.DetectFormat(path, scriptBytes) != script.FormatYAML {
+			return fmt.Errorf("could not rewrite %v; only local YAML files can be rewritten", path)
+		}
+
+		indirections, err := parser.Indirections(scriptBytes)
+
+		if err != nil {
+			return err
+		}
+
+		if len(indirections) > 0 {
+			return fmt.Errorf("could not rewrite %v, as formatting resolves its %v; print it instead", path, strings.Join(indirections, ", "))
+		}
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return err
+		}
+
+		if err = os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
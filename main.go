@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -23,16 +26,28 @@ import (
 )
 
 type options struct {
-	Version         bool          `short:"V" long:"version" description:"Print version information and exit"`
-	Verbose         bool          `short:"v" long:"verbose" description:"Print verbose information"`
-	Kiosk           bool          `short:"k" long:"kiosk" description:"Run in kiosk mode"`
-	Headless        bool          `short:"H" long:"headless" description:"Run headless"`
-	Interval        time.Duration `short:"i" long:"interval" description:"how long to wait before switching to the next tab. Anything Go's time#ParseDuration understands is accepted." default:"5s"`
-	HttpBindAddress string        `short:"a" long:"http-address" description:"Address to bind the HTTP control server to" default:"localhost:8011"`
-	ChromeFlags     []string      `long:"chrome-flag" description:"additional flags to pass to chromium"`
-	Args            struct {
-		Scriptfile string
-	} `positional-args:"yes"`
+	Version          bool          `short:"V" long:"version" description:"Print version information and exit"`
+	Verbose          bool          `short:"v" long:"verbose" description:"Print verbose information"`
+	Kiosk            bool          `short:"k" long:"kiosk" description:"Run in kiosk mode"`
+	Headless         bool          `short:"H" long:"headless" description:"Run headless"`
+	Interval         time.Duration `short:"i" long:"interval" description:"how long to wait before switching to the next tab. Anything Go's time#ParseDuration understands is accepted." default:"5s"`
+	HttpBindAddress  string        `short:"a" long:"http-address" description:"Address to bind the HTTP control server to" default:"localhost:8011"`
+	ChromeFlags      []string      `long:"chrome-flag" description:"additional flags to pass to chromium"`
+	Variables        []string      `long:"var" description:"set a script variable, overriding the one in the script; expecting name=value"`
+	SecretsDir       string        `long:"secrets-dir" description:"directory to resolve relative secret files against (default: $CREDENTIALS_DIRECTORY or /run/secrets)"`
+	PollInterval     time.Duration `long:"poll-interval" description:"how often to check a script given as URL for changes; 0 disables polling" default:"1m"`
+	RemoteURL        string        `long:"remote-debugging-url" description:"attach to a running Chromium with remote debugging enabled at this URL, e.g. http://localhost:9222, instead of starting one"`
+	RemoteTargets    string        `long:"remote-targets-file" description:"file to record the tabs opened in the browser at --remote-debugging-url in, so that a restarted kiosk takes them over again (default: $STATE_DIRECTORY/remote-targets, or kiosk/remote-targets in the user's cache directory)"`
+	ProfileDir       string        `long:"profile-dir" description:"directory to keep the Chromium profile in, so that logins survive restarts (default: a new temporary directory)"`
+	AnnouncementsDir string        `long:"announcements-dir" description:"directory to keep announcements edited via HTTP in, so that they survive restarts (default: $STATE_DIRECTORY/announcements, or nowhere)"`
+
+	Validate validateCommand `command:"validate" description:"Check scripts thoroughly without starting the browser"`
+	Schema   schemaCommand   `command:"schema" description:"Print the JSON Schema of the script format"`
+	Explain  explainCommand  `command:"explain" description:"Print the resolved tabs and steps of a script without starting the browser"`
+	Try      tryCommand      `command:"try" description:"Run the steps of one tab in a headless browser and write a report"`
+	Fmt      fmtCommand      `command:"fmt" description:"Print scripts in canonical YAML"`
+	Policy   policyCommand   `command:"policy" description:"Print the Chromium policy selecting the client certificates of the tabs"`
+	Encrypt  encryptCommand  `command:"encrypt" description:"Encrypt a secret read from STDIN for an 'encrypted' secret"`
 }
 
 func (o options) String() string {
@@ -52,10 +67,23 @@ var htmlAssets embed.FS
 func main() {
 	logger := log.New(os.Stderr, "MAIN ", 0)
 
-	_, err := flags.Parse(&opts)
+	cli := flags.NewParser(&opts, flags.Default)
+	// the script file is not a positional argument, as it would shadow the commands
+	cli.Usage = "[OPTIONS] [Scriptfile]"
+	cli.SubcommandsOptional = true
+	args, err := cli.Parse()
 
 	if err != nil {
-		logger.Fatal(err)
+		if flags.WroteHelp(err) {
+			os.Exit(0)
+		}
+
+		os.Exit(1)
+	}
+
+	if cli.Active != nil {
+		// the command has already been executed
+		os.Exit(0)
 	}
 
 	if opts.Version {
@@ -67,97 +95,279 @@ func main() {
 		logger.Printf("starting with options: %v\n", opts)
 	}
 
+	if opts.SecretsDir != "" {
+		script.SecretsDirectory = opts.SecretsDir
+	}
+
+	if len(args) > 1 {
+		logger.Fatalf("Expecting at most one scriptfile, but got %v\n", len(args))
+	}
+
+	var scriptfile string
+
+	if len(args) == 1 {
+		scriptfile = args[0]
+	}
+
+	var remote *script.Remote
 	var scriptBytes []byte
 
-	if opts.Args.Scriptfile == "" {
-		if opts.Verbose {
-			log.Println("Reading script from STDIN")
-		}
-		scriptBytes, err = io.ReadAll(os.Stdin)
+	if script.IsURL(scriptfile) {
+		remote = script.NewRemote(scriptfile)
+		scriptBytes, _, err = remote.Fetch(context.Background())
 	} else {
-		if opts.Verbose {
-			log.Printf("Reading script from %v\n", opts.Args.Scriptfile)
-		}
-		scriptBytes, err = os.ReadFile(opts.Args.Scriptfile)
+		scriptBytes, err = readScript(scriptfile)
 	}
 
 	if err != nil {
 		log.Fatalf("Could not read scriptfile: %v\n", err)
 	}
 
-	tabs, err := script.Parse(scriptBytes)
+	parser, err := newScriptParser(scriptfile)
 
 	if err != nil {
-		log.Fatalf("Could not parse scriptfile %v: %v\n", opts.Args.Scriptfile, err)
+		log.Fatal(err)
 	}
 
-	statusUpdates := make(chan controller.StatusUpdate, 10)
+	if remote != nil {
+		parser = parser.WithFormat(remote.Format())
+	}
 
-	kiosk := controller.NewKiosk().
-		WithInterval(opts.Interval).
-		WithFullScreen(opts.Kiosk).
-		WithHeadless(opts.Headless).
-		WithStatusUpdates(statusUpdates)
+	screens, err := parser.ParseScreens(scriptBytes)
 
-	for _, cf := range opts.ChromeFlags {
-		key, value, found := strings.Cut(cf, "=")
+	if err != nil {
+		log.Fatalf("Could not parse scriptfile:\n%v\n", err)
+	}
 
-		if !found {
-			log.Fatalf("Could not separate chrome flag %v; expecting k=v\n", cf)
-		}
+	if opts.RemoteURL != "" && len(screens) > 1 {
+		log.Fatalf("Could not attach to the browser at --remote-debugging-url; it can show only one screen, but the script has %v\n", len(screens))
+	}
 
-		kiosk = kiosk.WithFlag(key, value)
+	if opts.RemoteURL != "" && (len(opts.ChromeFlags) > 0 || opts.ProfileDir != "" || opts.Kiosk || opts.Headless) {
+		log.Println("Ignoring --chrome-flag, --profile-dir, --kiosk and --headless, as the browser at --remote-debugging-url is started elsewhere")
 	}
 
-	for _, tab := range tabs {
-		if opts.Verbose {
-			log.Printf("Performing actions for tab %s:\n", tab)
+	// media and announcement tabs are served by the HTTP server, so it starts before the tabs are opened
+	listener, err := net.Listen("tcp", opts.HttpBindAddress)
 
-			for _, a := range tab.Steps {
-				log.Printf("       * %s\n", a)
-			}
-		}
+	if err != nil {
+		log.Fatalf("Could not start the HTTP control server: %v\n", err)
+	}
+
+	mediaServer := controller.NewMediaServer(localURL(listener.Addr()))
+	http.Handle("/media/", mediaServer)
+
+	announcementServer := controller.NewAnnouncementServer(localURL(listener.Addr()), announcementsDir())
+	http.Handle("/announcements", announcementServer)
+	http.Handle("/announcements/", announcementServer)
 
-		err = kiosk.NewTab(tab)
+	go func() {
+		log.Printf("HTTP control server starting at http://%v\n", opts.HttpBindAddress)
+		log.Fatal(http.Serve(listener, nil))
+	}()
+
+	statusUpdates := make(chan controller.StatusUpdate, 10)
+	var kiosks kiosks
+
+	for _, screen := range screens {
+		kiosk, err := newKiosk(screen, mediaServer, announcementServer, statusUpdates)
 
 		if err != nil {
 			log.Fatal(err)
 		}
+
+		kiosks = append(kiosks, kiosk)
 	}
 
 	if opts.Verbose {
 		logger.Println("starting tab switching")
 	}
 
-	kiosk.StartTabSwitching()
+	for _, kiosk := range kiosks {
+		kiosk.StartTabSwitching()
+	}
+
+	if remote != nil && opts.PollInterval > 0 {
+		watchIncludes(remote, parser, scriptBytes, logger)
+		go pollScript(remote, kiosks, logger)
+	}
 
 	quitProgram := make(chan struct{})
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		kiosk.Close()
+
+		for _, kiosk := range kiosks {
+			kiosk.Close()
+		}
+
 		close(quitProgram)
 	}()
 
 	weblogger := log.New(os.Stderr, "WEB ", 0)
 
-	http.Handle("/", createRootHandler(kiosk, weblogger))
-	http.Handle("/image/", createImageHandler(kiosk, weblogger))
-	http.Handle("/activate/", createActivateHandler(kiosk, weblogger))
-	http.Handle("/pause", createPauseHandler(kiosk, weblogger))
-	http.Handle("/resume", createResumeHandler(kiosk, weblogger))
-	http.Handle("/updates", createUpdateHandler(kiosk, weblogger, statusUpdates))
+	http.Handle("/", createRootHandler(kiosks, weblogger))
+	http.Handle("/image/", createImageHandler(kiosks, weblogger))
+	http.Handle("/activate/", createActivateHandler(kiosks, weblogger))
+	http.Handle("/pause", createPauseHandler(kiosks, weblogger))
+	http.Handle("/resume", createResumeHandler(kiosks, weblogger))
+	http.Handle("/updates", createUpdateHandler(weblogger, statusUpdates))
+	http.Handle("/status", createStatusHandler(kiosks, weblogger))
+	http.Handle("/profile", createProfileHandler(kiosks, weblogger))
 	http.Handle("/backlight", createBacklightHandlers(weblogger, statusUpdates))
-
-	go func() {
-		log.Printf("HTTP control server starting at http://%v\n", opts.HttpBindAddress)
-		log.Fatal(http.ListenAndServe(opts.HttpBindAddress, nil))
-	}()
+	http.Handle("/takeover", createTakeoverHandler(kiosks, weblogger, statusUpdates))
 
 	<-quitProgram
 }
 
+// readScript reads the script at path, which may be a URL, or from STDIN if path is empty or "-".
+func readScript(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		if opts.Verbose {
+			log.Println("Reading script from STDIN")
+		}
+
+		return io.ReadAll(os.Stdin)
+	}
+
+	if opts.Verbose {
+		log.Printf("Reading script from %v\n", path)
+	}
+
+	if script.IsURL(path) {
+		content, _, err := script.NewRemote(path).Fetch(context.Background())
+		return content, err
+	}
+
+	return os.ReadFile(path)
+}
+
+// pollScript fetches the script every --poll-interval and replaces the tabs of the kiosks if it changed.
+func pollScript(remote *script.Remote, kiosks kiosks, logger *log.Logger) {
+	for range time.Tick(opts.PollInterval) {
+		scriptBytes, changed, err := remote.Fetch(context.Background())
+
+		if err != nil {
+			logger.Printf("Could not fetch script: %v\n", err)
+			continue
+		}
+
+		if !changed {
+			continue
+		}
+
+		parser, err := newScriptParser(remote.URL())
+
+		if err != nil {
+			logger.Printf("Could not parse changed script: %v\n", err)
+			continue
+		}
+
+		screens, err := parser.WithFormat(remote.Format()).ParseScreens(scriptBytes)
+
+		if err != nil {
+			logger.Printf("Keeping the current tabs, as the changed script could not be parsed:\n%v\n", err)
+			continue
+		}
+
+		watchIncludes(remote, parser, scriptBytes, logger)
+
+		if !kiosks.show(screens) {
+			logger.Println("Keeping the current tabs, as the screens of the changed script differ; restart to apply them")
+			continue
+		}
+
+		for i, screen := range screens {
+			logger.Printf("Script changed; replacing the tabs of %v with %v new ones\n", screen, len(screen.Tabs))
+
+			if err := kiosks[i].ReplaceTabs(screen.Tabs); err != nil {
+				logger.Printf("Could not replace tabs: %v\n", err)
+			}
+		}
+	}
+}
+
+// watchIncludes makes polling notice changes of the files the script includes
+// via HTTP, too.
+func watchIncludes(remote *script.Remote, parser *script.Parser, scriptBytes []byte, logger *log.Logger) {
+	includes, err := parser.Includes(scriptBytes)
+
+	if err != nil {
+		logger.Printf("Could not watch the files the script includes: %v\n", err)
+		return
+	}
+
+	remote.WatchIncludes(includes)
+}
+
+// announcementsDir is where announcements edited via HTTP are kept; empty
+// if nowhere.
+func announcementsDir() string {
+	if opts.AnnouncementsDir != "" {
+		return opts.AnnouncementsDir
+	}
+
+	// set by systemd for services with StateDirectory=
+	if stateDir := os.Getenv("STATE_DIRECTORY"); stateDir != "" {
+		return filepath.Join(stateDir, "announcements")
+	}
+
+	return ""
+}
+
+// remoteTargetsFile is where the tabs opened in the browser at
+// --remote-debugging-url are recorded; empty if nowhere.
+func remoteTargetsFile() string {
+	if opts.RemoteTargets != "" {
+		return opts.RemoteTargets
+	}
+
+	// set by systemd for services with StateDirectory=
+	if stateDir := os.Getenv("STATE_DIRECTORY"); stateDir != "" {
+		return filepath.Join(stateDir, "remote-targets")
+	}
+
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "kiosk", "remote-targets")
+	}
+
+	return ""
+}
+
+// localURL is the URL the browser reaches the HTTP control server at, which
+// may be listening on all addresses.
+func localURL(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+
+	if ok && tcpAddr.IP.IsUnspecified() {
+		return fmt.Sprintf("http://localhost:%v", tcpAddr.Port)
+	}
+
+	return "http://" + addr.String()
+}
+
+// newScriptParser creates a parser for the script at path with the variables given on the command line.
+func newScriptParser(path string) (*script.Parser, error) {
+	if path == "-" {
+		path = ""
+	}
+
+	parser := script.NewParser().WithPath(path)
+
+	for _, v := range opts.Variables {
+		name, value, found := strings.Cut(v, "=")
+
+		if !found {
+			return nil, fmt.Errorf("could not separate variable %v; expecting name=value", v)
+		}
+
+		parser = parser.WithVariable(name, value)
+	}
+
+	return parser, nil
+}
+
 func getProgramName() string {
 	path, err := os.Executable()
 
@@ -173,7 +383,14 @@ func getProgramVersion() string {
 	return fmt.Sprintf("%s %s (%s), built on %s", getProgramName(), version, commit, date)
 }
 
-func createRootHandler(kiosk *controller.Kiosk, logger *log.Logger) http.HandlerFunc {
+// screenView is what the index page shows of a screen.
+type screenView struct {
+	Name           string
+	Images         []string
+	IsTabSwitching bool
+}
+
+func createRootHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
 	tmpl, err := template.ParseFS(htmlAssets, "index.html.tmpl")
 
 	if err != nil {
@@ -186,20 +403,29 @@ func createRootHandler(kiosk *controller.Kiosk, logger *log.Logger) http.Handler
 			return
 		}
 
+		var screens []screenView
+
+		for _, kiosk := range kiosks {
+			screens = append(screens, screenView{
+				Name:           kiosk.Name(),
+				Images:         kiosk.ImageIDs(),
+				IsTabSwitching: kiosk.IsTabSwitching(),
+			})
+		}
+
 		w.Header().Set("Content-Type", "text/html")
 		tmpl.Execute(w, map[string]any{
 			"programVersion": getProgramVersion(),
-			"images":         kiosk.ImageIDs(),
-			"isTabSwitching": kiosk.IsTabSwitching(),
+			"screens":        screens,
 		})
 	}
 }
 
-func createImageHandler(kiosk *controller.Kiosk, logger *log.Logger) http.HandlerFunc {
+func createImageHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		imageID := strings.TrimPrefix(r.URL.Path, "/image/")
 
-		img, found := kiosk.GetImage(imageID)
+		img, found := kiosks.image(imageID)
 
 		if !found {
 			msg := fmt.Sprintf("no image for target ID %v", imageID)
@@ -213,7 +439,7 @@ func createImageHandler(kiosk *controller.Kiosk, logger *log.Logger) http.Handle
 	}
 }
 
-func createActivateHandler(kiosk *controller.Kiosk, logger *log.Logger) http.HandlerFunc {
+func createActivateHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, `{"error": "Only POST allowed here"}`, http.StatusMethodNotAllowed)
@@ -232,8 +458,21 @@ func createActivateHandler(kiosk *controller.Kiosk, logger *log.Logger) http.Han
 			logger.Printf("switching to tab %v", targetID)
 		}
 
+		kiosk, found := kiosks.withTab(targetID)
+
+		if !found {
+			logger.Printf("no tab with ID %v", targetID)
+			http.Error(w, `{"error": "no such tab"}`, http.StatusNotFound)
+			return
+		}
+
 		err := kiosk.SwitchToTab(targetID)
 
+		if errors.Is(err, controller.ErrTakenOver) {
+			http.Error(w, `{"error": "the screen is taken over"}`, http.StatusConflict)
+			return
+		}
+
 		if err != nil {
 			logger.Printf("could not switch to tab: %v", err)
 			http.Error(w, `{"error": "could not switch to tab"}`, http.StatusInternalServerError)
@@ -244,37 +483,49 @@ func createActivateHandler(kiosk *controller.Kiosk, logger *log.Logger) http.Han
 	}
 }
 
-func createPauseHandler(kiosk *controller.Kiosk, logger *log.Logger) http.HandlerFunc {
+func createPauseHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, `{"error": "Only POST allowed here"}`, http.StatusMethodNotAllowed)
 			return
 		}
 
-		logger.Println("pausing tab switching")
+		kiosk, found := kiosks.fromRequest(w, r, logger)
+
+		if !found {
+			return
+		}
+
+		logger.Printf("pausing tab switching of %v", describeScreen(kiosk))
 		kiosk.PauseTabSwitching()
 		w.WriteHeader(http.StatusCreated)
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"isTabSwitching": %v}`, kiosk.IsTabSwitching())
+		json.NewEncoder(w).Encode(map[string]any{"screen": kiosk.Name(), "isTabSwitching": kiosk.IsTabSwitching()})
 	}
 }
 
-func createResumeHandler(kiosk *controller.Kiosk, logger *log.Logger) http.HandlerFunc {
+func createResumeHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, `{"error": "Only POST allowed here"}`, http.StatusMethodNotAllowed)
 			return
 		}
 
-		logger.Println("resuming tab switching")
+		kiosk, found := kiosks.fromRequest(w, r, logger)
+
+		if !found {
+			return
+		}
+
+		logger.Printf("resuming tab switching of %v", describeScreen(kiosk))
 		kiosk.StartTabSwitching()
 		w.WriteHeader(http.StatusCreated)
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"isTabSwitching": %v}`, kiosk.IsTabSwitching())
+		json.NewEncoder(w).Encode(map[string]any{"screen": kiosk.Name(), "isTabSwitching": kiosk.IsTabSwitching()})
 	}
 }
 
-func createUpdateHandler(kiosk *controller.Kiosk, logger *log.Logger, statusUpdates chan controller.StatusUpdate) http.HandlerFunc {
+func createUpdateHandler(logger *log.Logger, statusUpdates chan controller.StatusUpdate) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
@@ -299,6 +550,58 @@ func createUpdateHandler(kiosk *controller.Kiosk, logger *log.Logger, statusUpda
 	}
 }
 
+func createStatusHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, `{"error": "Only GET allowed here"}`, http.StatusMethodNotAllowed)
+			return
+		}
+
+		kiosk, found := kiosks.fromRequest(w, r, logger)
+
+		if !found {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(kiosk.Status())
+
+		if err != nil {
+			logger.Println(err)
+			http.Error(w, `{"error": "unable to encode status"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// createProfileHandler clears the profile of the browser on DELETE, e.g. if
+// a login got stuck. Clearing makes the tabs log in again.
+func createProfileHandler(kiosks kiosks, logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, `{"error": "Only DELETE allowed here"}`, http.StatusMethodNotAllowed)
+			return
+		}
+
+		kiosk, found := kiosks.fromRequest(w, r, logger)
+
+		if !found {
+			return
+		}
+
+		logger.Printf("clearing the profile of %v", describeScreen(kiosk))
+
+		if err := kiosk.ClearProfile(); err != nil {
+			logger.Printf("could not clear the profile: %v", err)
+			http.Error(w, `{"error": "could not clear the profile"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// the scripts of the tabs run again in the background
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
 func createBacklightHandlers(logger *log.Logger, statusUpdates chan controller.StatusUpdate) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -397,7 +700,12 @@ func backlightPostHandler(w http.ResponseWriter, r *http.Request, logger *log.Lo
 }
 
 func eachDisplay(callback func(id uint8) (bool, error)) (displayStati []*videocore.DisplayStatus, err error) {
-	displays, err := videocore.GetDisplays()
+	return eachDisplayOf(videocore.GetDisplays, callback)
+}
+
+// eachDisplayOf calls back for each of the displays that getDisplays returns.
+func eachDisplayOf(getDisplays func() ([]uint8, error), callback func(id uint8) (bool, error)) (displayStati []*videocore.DisplayStatus, err error) {
+	displays, err := getDisplays()
 
 	if err != nil {
 		return
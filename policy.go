@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"uhlig.it/kiosk/script"
+)
+
+type policyCommand struct {
+	Args struct {
+		Scriptfile string `description:"script to read the certificates from; reads from STDIN if not given"`
+	} `positional-args:"yes"`
+}
+
+// Execute prints the Chromium policy that selects the client certificates of
+// the tabs. DevTools has no way to select them, so the policy needs to be
+// installed along with the kiosk.
+func (c *policyCommand) Execute(args []string) error {
+	scriptBytes, err := readScript(c.Args.Scriptfile)
+
+	if err != nil {
+		return fmt.Errorf("could not read scriptfile: %w", err)
+	}
+
+	parser, err := newScriptParser(c.Args.Scriptfile)
+
+	if err != nil {
+		return err
+	}
+
+	tabs, err := parser.Parse(scriptBytes)
+
+	if err != nil {
+		return err
+	}
+
+	policy, err := script.CertificatePolicy(tabs)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(string(policy))
+	return err
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"uhlig.it/kiosk/script"
+)
+
+type schemaCommand struct{}
+
+// Execute prints the JSON Schema of the script format.
+func (c *schemaCommand) Execute(args []string) error {
+	schema, err := script.Schema()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(string(schema))
+	return err
+}
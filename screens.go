@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+// kiosks has one kiosk for each screen of the script, the default screen first.
+type kiosks []*controller.Kiosk
+
+// newKiosk starts a browser for the screen and opens its tabs. Each screen
+// has a browser of its own, as the windows of one browser cannot be placed
+// independently.
+func newKiosk(screen *script.Screen, mediaServer *controller.MediaServer, announcementServer *controller.AnnouncementServer, statusUpdates chan controller.StatusUpdate) (*controller.Kiosk, error) {
+	interval := opts.Interval
+
+	if screen.Interval != 0 {
+		interval = screen.Interval
+	}
+
+	// browsers cannot share a profile
+	profileDir := opts.ProfileDir
+
+	if profileDir != "" && !screen.IsDefault() {
+		profileDir = fmt.Sprintf("%v-%v", strings.TrimRight(profileDir, "/"), screen.Name)
+	}
+
+	kiosk := controller.NewKiosk().
+		WithName(screen.Name).
+		WithInterval(interval).
+		WithFullScreen(opts.Kiosk).
+		WithHeadless(opts.Headless).
+		WithUserDataDir(profileDir).
+		WithRemoteDebuggingURL(opts.RemoteURL).
+		WithTargetsFile(remoteTargetsFile()).
+		WithMediaServer(mediaServer).
+		WithAnnouncementServer(announcementServer).
+		WithStatusUpdates(statusUpdates)
+
+	for _, cf := range opts.ChromeFlags {
+		key, value, found := strings.Cut(cf, "=")
+
+		if !found {
+			return nil, fmt.Errorf("could not separate chrome flag %v; expecting k=v", cf)
+		}
+
+		kiosk = kiosk.WithFlag(key, value)
+	}
+
+	// the screen takes precedence over --chrome-flag
+	kiosk = kiosk.WithWindow(screen.Position.X, screen.Position.Y, screen.Size.Width, screen.Size.Height)
+
+	for _, tab := range screen.Tabs {
+		if opts.Verbose {
+			log.Printf("Performing actions for tab %s of %v:\n", tab, screen)
+
+			for _, a := range tab.Steps {
+				log.Printf("       * %s\n", a)
+			}
+		}
+
+		if err := kiosk.NewTab(tab); err != nil {
+			return nil, err
+		}
+	}
+
+	kiosk.CloseLeftoverTabs()
+
+	return kiosk, nil
+}
+
+// find returns the kiosk of the screen with the given name; the empty name
+// is the first screen.
+func (kk kiosks) find(name string) (*controller.Kiosk, bool) {
+	if name == "" && len(kk) > 0 {
+		return kk[0], true
+	}
+
+	for _, kiosk := range kk {
+		if kiosk.Name() == name {
+			return kiosk, true
+		}
+	}
+
+	return nil, false
+}
+
+// fromRequest finds the kiosk of the screen given as parameter 'screen' of
+// the request. It answers the request itself if there is no such screen.
+func (kk kiosks) fromRequest(w http.ResponseWriter, r *http.Request, logger *log.Logger) (*controller.Kiosk, bool) {
+	if err := r.ParseForm(); err != nil {
+		logger.Printf("could not parse form parameters: %v", err)
+		http.Error(w, `{"error": "could not parse form parameters"}`, http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	name := r.FormValue("screen")
+	kiosk, found := kk.find(name)
+
+	if !found {
+		logger.Printf("no screen named %v", name)
+		http.Error(w, `{"error": "no such screen"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	return kiosk, true
+}
+
+// withTab returns the kiosk showing the tab with the given target ID.
+func (kk kiosks) withTab(targetID string) (*controller.Kiosk, bool) {
+	for _, kiosk := range kk {
+		if slices.Contains(kiosk.ImageIDs(), targetID) {
+			return kiosk, true
+		}
+	}
+
+	return nil, false
+}
+
+func (kk kiosks) image(targetID string) (*controller.Image, bool) {
+	for _, kiosk := range kk {
+		if img, found := kiosk.GetImage(targetID); found {
+			return img, true
+		}
+	}
+
+	return nil, false
+}
+
+// show tells whether the kiosks show the given screens, in the same order.
+func (kk kiosks) show(screens []*script.Screen) bool {
+	return slices.EqualFunc(kk, screens, func(kiosk *controller.Kiosk, screen *script.Screen) bool {
+		return kiosk.Name() == screen.Name
+	})
+}
+
+func describeScreen(kiosk *controller.Kiosk) string {
+	if kiosk.Name() == "" {
+		return "the default screen"
+	}
+
+	return fmt.Sprintf("screen '%v'", kiosk.Name())
+}
@@ -0,0 +1,68 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+// Announcement is a short message shown by a tab, written in Markdown or
+// HTML. Both are Go templates, which get the current time, the hostname and
+// the JSON document at Data.
+type Announcement struct {
+	Markdown string
+	HTML     string
+	// Data is the URL of a JSON document, which the template gets as .Data.
+	Data string
+	// Refresh renders the announcement again this often; 0 keeps the default.
+	Refresh time.Duration
+}
+
+// Template returns the Markdown or the HTML, whichever is given.
+func (a *Announcement) Template() string {
+	if a.Markdown != "" {
+		return a.Markdown
+	}
+
+	return a.HTML
+}
+
+func (a *Announcement) Validate() error {
+	if (a.Markdown == "") == (a.HTML == "") {
+		return errors.New("an announcement needs either 'markdown' or 'html'")
+	}
+
+	if _, err := template.New("announcement").Parse(a.Template()); err != nil {
+		return fmt.Errorf("unable to parse the template of the announcement: %w", err)
+	}
+
+	if a.Data != "" {
+		u, err := url.Parse(a.Data)
+
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("'%v' is not an absolute HTTP URL", a.Data)
+		}
+	}
+
+	if a.Refresh < 0 {
+		return errors.New("refresh of an announcement must not be negative")
+	}
+
+	return nil
+}
+
+func (a *Announcement) String() string {
+	format := "HTML"
+
+	if a.Markdown != "" {
+		format = "Markdown"
+	}
+
+	if a.Data != "" {
+		return fmt.Sprintf("an announcement in %v with data from %v", format, a.Data)
+	}
+
+	return fmt.Sprintf("an announcement in %v", format)
+}
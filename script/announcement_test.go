@@ -0,0 +1,83 @@
+package script_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Announcement", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Welcome
+  announce:
+    markdown: |
+      # Welcome
+      It is {{ .Time.Format "15:04" }} on {{ .Hostname }}.
+- name: Lunch
+  announce:
+    html: <h1>{{ .Data.menu }}</h1>
+    data: https://canteen.example.com/today.json
+    refresh: 5m
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(2))
+
+		Expect(tabs[0].Announcement.Markdown).To(Equal("# Welcome\nIt is {{ .Time.Format \"15:04\" }} on {{ .Hostname }}.\n"))
+		Expect(tabs[0].Steps).To(BeEmpty())
+		Expect(tabs[1].Announcement.HTML).To(Equal("<h1>{{ .Data.menu }}</h1>"))
+		Expect(tabs[1].Announcement.Data).To(Equal("https://canteen.example.com/today.json"))
+		Expect(tabs[1].Announcement.Refresh).To(Equal(5 * time.Minute))
+	})
+
+	It("round-trips", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Welcome
+  announce:
+    markdown: |
+      # Welcome
+      It costs $$5.
+    refresh: 30s
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Announcement.Markdown).To(Equal("# Welcome\nIt costs $5.\n"))
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+
+		reparsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reparsed).To(Equal(tabs))
+	})
+
+	It("rejects the same name on two announcements", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Welcome
+  announce: { markdown: Hello }
+- name: Welcome
+  announce: { markdown: Hi }
+`))
+		Expect(err).To(MatchError("5:13: tab 'Welcome': announcement 'Welcome' is defined more than once"))
+		Expect(tabs).To(BeEmpty())
+	})
+
+	DescribeTable("rejected",
+		func(tab string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- ` + tab + `
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("no name", `announce: { markdown: Hello }`, "2:13: tab '#1': an announcement needs the tab to have a name"),
+		Entry("neither markdown nor html", `{ name: Welcome, announce: { refresh: 1m } }`, "2:30: tab 'Welcome': an announcement needs either 'markdown' or 'html'"),
+		Entry("both markdown and html", `{ name: Welcome, announce: { markdown: Hello, html: Hello } }`, "2:30: tab 'Welcome': an announcement needs either 'markdown' or 'html'"),
+		Entry("unknown key", `{ name: Welcome, announce: { markdown: Hello, text: Hello } }`, "2:49: tab 'Welcome': 'text' is not a known key for an announcement"),
+		Entry("broken template", `{ name: Welcome, announce: { markdown: "{{ .Time" } }`, "2:30: tab 'Welcome': unable to parse the template of the announcement: template: announcement:1: unclosed action"),
+		Entry("relative data URL", `{ name: Welcome, announce: { markdown: Hello, data: today.json } }`, "2:30: tab 'Welcome': 'today.json' is not an absolute HTTP URL"),
+		Entry("unparsable refresh", `{ name: Welcome, announce: { markdown: Hello, refresh: often } }`, `2:58: tab 'Welcome': unable to parse 'often' as refresh of an announcement: time: invalid duration "often"`),
+		Entry("and a script", `{ name: Welcome, announce: { markdown: Hello }, script: [ go: "https://example.com" ] }`, "2:30: tab 'Welcome': an announcement tab has neither a script, a layout nor media"),
+	)
+})
@@ -0,0 +1,132 @@
+package script
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chromedp/cdproto/fetch"
+)
+
+// Auth gets a tab past HTTP authentication, which Chromium would otherwise
+// ask for in a dialog.
+type Auth struct {
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	// Origin is the only one the credentials are sent to. It defaults to
+	// the origin of the URL of the tab.
+	Origin string `yaml:"origin"`
+	// Certificate selects the client certificate for mutual TLS.
+	Certificate CertificateFilter `yaml:"certificate"`
+}
+
+// CertificateFilter selects a client certificate by the common names of its
+// issuer and subject. Chromium cannot be told via DevTools, so the filters
+// become a policy (see CertificatePolicy).
+type CertificateFilter struct {
+	Issuer  string `yaml:"issuer"`
+	Subject string `yaml:"subject"`
+}
+
+func (f CertificateFilter) IsZero() bool {
+	return f.Issuer == "" && f.Subject == ""
+}
+
+func (a *Auth) Validate() error {
+	if a.Username == "" && a.Certificate.IsZero() {
+		return errors.New("auth needs a 'username' or a 'certificate'")
+	}
+
+	if a.Username == "" && !a.Password.IsZero() {
+		return errors.New("a password needs a username")
+	}
+
+	if !a.Password.IsZero() {
+		if err := a.Password.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if a.Origin != "" {
+		if o, err := origin(a.Origin); err != nil || o != a.Origin {
+			return fmt.Errorf("'%v' is not an origin like https://example.com", a.Origin)
+		}
+	}
+
+	return nil
+}
+
+// origin the credentials and the certificate are meant for.
+func (n *Tab) authOrigin() string {
+	if n.Auth.Origin != "" {
+		return n.Auth.Origin
+	}
+
+	o, _ := origin(n.URL())
+
+	return o
+}
+
+// respond answers a challenge with the credentials if it comes from the
+// origin of the tab. If the credentials were rejected already, retrying them
+// would only make the server ask again.
+func (n *Tab) respond(challenge *fetch.AuthChallenge, rejected bool) *fetch.AuthChallengeResponse {
+	if n.Auth.Username == "" || challenge.Source == fetch.AuthChallengeSourceProxy || challenge.Origin != n.authOrigin() {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	}
+
+	if rejected {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+
+	password, err := n.Auth.Password.Resolve()
+
+	if err != nil {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: n.Auth.Username,
+		Password: password,
+	}
+}
+
+// CertificatePolicy returns a Chromium policy that selects the client
+// certificates of the tabs without asking. It needs to be installed as
+// managed policy, e.g. in /etc/chromium/policies/managed/.
+func CertificatePolicy(tabs []*Tab) ([]byte, error) {
+	patterns := []string{}
+
+	for _, tab := range tabs {
+		if tab.Auth == nil || tab.Auth.Certificate.IsZero() {
+			continue
+		}
+
+		filter := make(map[string]map[string]string)
+
+		if tab.Auth.Certificate.Issuer != "" {
+			filter["ISSUER"] = map[string]string{"CN": tab.Auth.Certificate.Issuer}
+		}
+
+		if tab.Auth.Certificate.Subject != "" {
+			filter["SUBJECT"] = map[string]string{"CN": tab.Auth.Certificate.Subject}
+		}
+
+		// an empty pattern would select the certificate for every site
+		if tab.authOrigin() == "" {
+			return nil, fmt.Errorf("tab '%v': auth needs an 'origin' if the tab does not go to an absolute URL", tab.Name)
+		}
+
+		// every entry of the policy is JSON in itself
+		pattern, err := json.Marshal(map[string]any{"pattern": tab.authOrigin(), "filter": filter})
+
+		if err != nil {
+			return nil, err
+		}
+
+		patterns = append(patterns, string(pattern))
+	}
+
+	return json.MarshalIndent(map[string][]string{"AutoSelectCertificateForUrls": patterns}, "", "  ")
+}
@@ -0,0 +1,252 @@
+package script_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Auth", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Status
+  auth:
+    username: kiosk
+    password: env:STATUS_PASSWORD
+    origin: https://status.example.com:8443
+    certificate:
+      issuer: Example Internal CA
+      subject: lobby
+  script:
+    - go: https://status.example.com:8443/overview
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Auth).To(Equal(&script.Auth{
+			Username:    "kiosk",
+			Password:    script.ParseSecret("env:STATUS_PASSWORD"),
+			Origin:      "https://status.example.com:8443",
+			Certificate: script.CertificateFilter{Issuer: "Example Internal CA", Subject: "lobby"},
+		}))
+	})
+
+	DescribeTable("rejected",
+		func(auth string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Status
+  auth: ` + auth + `
+  script:
+    - go: https://status.example.com
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("auth that is not a map", `kiosk:s3cr3t`, "3:9: tab 'Status': unable to parse 'kiosk:s3cr3t' as auth"),
+		Entry("neither username nor certificate", `{ password: s3cr3t }`, "3:9: tab 'Status': auth needs a 'username' or a 'certificate'"),
+		Entry("password without username", `{ password: s3cr3t, certificate: { issuer: CA } }`, "3:9: tab 'Status': a password needs a username"),
+		Entry("origin with a path", `{ username: kiosk, origin: "https://example.com/status" }`, "3:9: tab 'Status': 'https://example.com/status' is not an origin like https://example.com"),
+		Entry("origin with the default port", `{ username: kiosk, origin: "https://example.com:443" }`, "3:9: tab 'Status': 'https://example.com:443' is not an origin like https://example.com"),
+		Entry("empty certificate", `{ certificate: {} }`, "3:24: tab 'Status': a certificate needs an 'issuer' or a 'subject'"),
+		Entry("unknown certificate key", `{ certificate: { serial: 42 } }`, "3:34: tab 'Status': unable to convert '42' as 'serial' value of a certificate"),
+		Entry("unknown key", `{ username: kiosk, realm: status }`, "3:28: tab 'Status': 'realm' is not a known key for auth"),
+	)
+
+	It("requires an origin for a tab without URL", func() {
+		_, err := script.Parse([]byte(`
+- name: Status
+  auth:
+    username: kiosk
+  script:
+    - wait: text:Welcome
+`))
+		Expect(err).To(MatchError("4:5: tab 'Status': auth needs an 'origin' if the tab does not go to an absolute URL"))
+	})
+
+	It("round-trips", func() {
+		tabs := []*script.Tab{{
+			Name: "Status",
+			Auth: &script.Auth{
+				Username:    "kiosk",
+				Password:    script.ParseSecret("file:status-password"),
+				Certificate: script.CertificateFilter{Issuer: "Example CA"},
+			},
+			Steps: []script.Step{script.Go("https://status.example.com")},
+		}}
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal(`- name: Status
+  auth:
+    username: kiosk
+    password: file:status-password
+    certificate:
+      issuer: Example CA
+  script:
+    - go: https://status.example.com
+`))
+
+		parsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(tabs))
+	})
+
+	Describe("certificate policy", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.NotFoundHandler())
+			DeferCleanup(server.Close)
+		})
+
+		It("selects the certificate for the origin of the tab", func() {
+			tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Status
+  auth:
+    certificate:
+      issuer: Example CA
+  script:
+    - go: %v/overview
+- name: Public
+  script:
+    - go: https://example.com
+`, server.URL)))
+			Expect(err).ToNot(HaveOccurred())
+
+			policy, err := script.CertificatePolicy(tabs)
+			Expect(err).ToNot(HaveOccurred())
+
+			var parsed map[string][]string
+			Expect(json.Unmarshal(policy, &parsed)).To(Succeed())
+			Expect(parsed["AutoSelectCertificateForUrls"]).To(HaveLen(1))
+			Expect(parsed["AutoSelectCertificateForUrls"][0]).To(MatchJSON(fmt.Sprintf(`{
+				"pattern": %q,
+				"filter": { "ISSUER": { "CN": "Example CA" } }
+			}`, server.URL)))
+		})
+
+		It("selects the certificate for the origin as the browser reports it", func() {
+			tabs, err := script.Parse([]byte(`
+- name: Status
+  auth:
+    certificate:
+      issuer: Example CA
+  script:
+    - go: https://Status.Example.com:443/overview
+`))
+			Expect(err).ToNot(HaveOccurred())
+
+			policy, err := script.CertificatePolicy(tabs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(policy)).To(ContainSubstring(`\"pattern\":\"https://status.example.com\"`))
+		})
+
+		It("selects the certificate a server requires", func() {
+			ca, caKey := certificate("Example CA", nil, nil)
+			client, clientKey := certificate("lobby", ca, caKey)
+
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(ca)
+
+			secured := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "Welcome")
+			}))
+			secured.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+			secured.StartTLS()
+			DeferCleanup(secured.Close)
+
+			tabs, err := script.Parse([]byte(fmt.Sprintf(`
+- name: Status
+  auth:
+    certificate:
+      issuer: Example CA
+      subject: lobby
+  script:
+    - go: %v/overview
+`, secured.URL)))
+			Expect(err).ToNot(HaveOccurred())
+
+			policy, err := script.CertificatePolicy(tabs)
+			Expect(err).ToNot(HaveOccurred())
+
+			var parsed map[string][]string
+			Expect(json.Unmarshal(policy, &parsed)).To(Succeed())
+			Expect(parsed["AutoSelectCertificateForUrls"]).To(ConsistOf(MatchJSON(fmt.Sprintf(`{
+				"pattern": %q,
+				"filter": { "ISSUER": { "CN": %q }, "SUBJECT": { "CN": %q } }
+			}`, secured.URL, client.Issuer.CommonName, client.Subject.CommonName))))
+
+			// the server turns away a browser without the certificate the policy selects
+			_, err = secured.Client().Get(secured.URL)
+			Expect(err).To(HaveOccurred())
+
+			withCertificate := secured.Client()
+			withCertificate.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{{
+				Certificate: [][]byte{client.Raw},
+				PrivateKey:  clientKey,
+			}}
+
+			response, err := withCertificate.Get(secured.URL)
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("needs the origin of the tab", func() {
+			_, err := script.CertificatePolicy([]*script.Tab{{
+				Name:  "Status",
+				Auth:  &script.Auth{Certificate: script.CertificateFilter{Issuer: "Example CA"}},
+				Steps: []script.Step{script.Go("/overview")},
+			}})
+			Expect(err).To(MatchError("tab 'Status': auth needs an 'origin' if the tab does not go to an absolute URL"))
+		})
+
+		It("is empty without certificates", func() {
+			policy, err := script.CertificatePolicy([]*script.Tab{{Name: "Public"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(MatchJSON(`{ "AutoSelectCertificateForUrls": [] }`))
+		})
+	})
+})
+
+// certificate creates a certificate with the given common name, signed by
+// the issuer, or by itself as a CA if there is no issuer.
+func certificate(commonName string, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		issuer, issuerKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	Expect(err).ToNot(HaveOccurred())
+
+	parsed, err := x509.ParseCertificate(raw)
+	Expect(err).ToNot(HaveOccurred())
+
+	return parsed, key
+}
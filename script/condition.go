@@ -0,0 +1,173 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// Condition is evaluated in the context of the tab when an If step runs.
+type Condition interface {
+	Check(ctx context.Context) (bool, error)
+	String() string
+	Validate() error
+}
+
+// ElementExists is true if the element is present right now. Unlike Wait, it does not wait for it.
+type ElementExists struct {
+	Selector
+}
+
+func (e ElementExists) Check(ctx context.Context) (bool, error) {
+	var nodes []*cdp.Node
+
+	opts := append(e.QueryOptions(), chromedp.AtLeast(0))
+	err := chromedp.Nodes(e.expression(), &nodes, opts...).Do(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(nodes) > 0, nil
+}
+
+func (e ElementExists) String() string {
+	return fmt.Sprintf("the element addressed by %v exists", e.Selector)
+}
+
+func (e ElementExists) Validate() error {
+	return e.Selector.Validate()
+}
+
+// URLMatches is true if the URL of the current page matches the regular expression.
+type URLMatches struct {
+	Pattern *regexp.Regexp
+}
+
+func (u URLMatches) Check(ctx context.Context) (bool, error) {
+	var location string
+
+	err := chromedp.Location(&location).Do(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return u.Pattern.MatchString(location), nil
+}
+
+func (u URLMatches) String() string {
+	return fmt.Sprintf("the URL matches '%v'", u.Pattern)
+}
+
+func (u URLMatches) Validate() error {
+	if u.Pattern == nil || u.Pattern.String() == "" {
+		return errors.New("pattern must not be empty")
+	}
+
+	return nil
+}
+
+// TextPresent is true if the text of the page contains the given text.
+type TextPresent string
+
+func (t TextPresent) Check(ctx context.Context) (bool, error) {
+	quoted, err := json.Marshal(string(t))
+
+	if err != nil {
+		return false, err
+	}
+
+	var present bool
+
+	err = chromedp.Evaluate(fmt.Sprintf("!!document.body && document.body.innerText.includes(%s)", quoted), &present).Do(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return present, nil
+}
+
+func (t TextPresent) String() string {
+	return fmt.Sprintf("the text '%v' is present", string(t))
+}
+
+func (t TextPresent) Validate() error {
+	if t == "" {
+		return errors.New("text must not be empty")
+	}
+
+	return nil
+}
+
+// If runs the Then steps if the condition holds, and the Else steps otherwise.
+type If struct {
+	Condition Condition
+	Then      []Step
+	Else      []Step
+}
+
+func (i *If) Action() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		holds, err := i.Condition.Check(ctx)
+
+		if err != nil {
+			return fmt.Errorf("could not check whether %v: %w", i.Condition, err)
+		}
+
+		steps := i.Else
+
+		if holds {
+			steps = i.Then
+		}
+
+		for _, step := range steps {
+			if err = step.Action().Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (i *If) String() string {
+	return fmt.Sprintf("if %v, %v; otherwise %v", i.Condition, describeSteps(i.Then), describeSteps(i.Else))
+}
+
+func (i *If) Validate() error {
+	if i.Condition == nil {
+		return errors.New("condition must not be empty")
+	}
+
+	if err := i.Condition.Validate(); err != nil {
+		return err
+	}
+
+	if len(i.Then) == 0 && len(i.Else) == 0 {
+		return errors.New("either then or else must have steps")
+	}
+
+	return nil
+}
+
+func describeSteps(steps []Step) string {
+	if len(steps) == 0 {
+		return "do nothing"
+	}
+
+	var descriptions []string
+
+	for _, step := range steps {
+		descriptions = append(descriptions, step.String())
+	}
+
+	return strings.Join(descriptions, ", then ")
+}
@@ -0,0 +1,162 @@
+package script_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("If", func() {
+	var scrpt []byte
+	var err error
+	var tabs []*script.Tab
+
+	JustBeforeEach(func() {
+		tabs, err = script.Parse(scrpt)
+	})
+
+	Context("with then and else", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+snippets:
+  login:
+    - type:
+        selector: css:#user
+        value: jdoe
+    - click: css:#submit
+tabs:
+  - name: Grafana
+    script:
+      - go: https://grafana.example.com
+      - if:
+          exists: css:#login
+        then:
+          - use: login
+        else:
+          - wait: css:.dashboard
+`)
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("has one step for the whole construct", func() {
+			Expect(tabs[0].Steps).To(HaveLen(2))
+		})
+
+		It("has the condition and both branches", func() {
+			ifStep, ok := tabs[0].Steps[1].(*script.If)
+			Expect(ok).To(BeTrue())
+			Expect(ifStep.Condition).To(Equal(script.ElementExists{Selector: script.Selector{Query: "#login", By: script.ByCSS}}))
+			Expect(ifStep.Then).To(HaveLen(2))
+			Expect(ifStep.Else).To(HaveLen(1))
+		})
+
+		It("presents itself as expected", func() {
+			Expect(tabs[0].Steps[1].String()).To(Equal("if the element addressed by css '#login' exists, type 'jdoe' into the element addressed by css '#user', then click the element addressed by css '#submit'; otherwise wait for the element addressed by css '.dashboard'"))
+		})
+	})
+
+	DescribeTable("conditions",
+		func(condition string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Conditions
+  script:
+    - if:
+        ` + condition + `
+      then:
+        - click: login
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs[0].Steps[0].String()).To(Equal("if " + expected + ", click the element addressed by 'login'; otherwise do nothing"))
+		},
+		Entry("element exists", "exists: //form", "the element addressed by '//form' exists"),
+		Entry("element exists with strategy", "exists: { selector: login, by: id }", "the element addressed by id 'login' exists"),
+		Entry("URL matches", "url: ^https://sso\\.", "the URL matches '^https://sso\\.'"),
+		Entry("text present", "text: Sign in", "the text 'Sign in' is present"),
+	)
+
+	DescribeTable("invalid",
+		func(step string, expected string) {
+			_, err := script.Parse([]byte(`
+- name: Invalid
+  script:
+` + step))
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("unknown condition",
+			"    - { if: { cookie: session }, then: [ { click: x } ] }\n",
+			"4:15: tab 'Invalid', step 1: 'cookie' is not a known condition"),
+		Entry("several conditions",
+			"    - { if: { text: a, url: b }, then: [ { click: x } ] }\n",
+			"4:13: tab 'Invalid', step 1: unable to parse 'map[text:a url:b]' as condition; expecting one of 'exists', 'url' or 'text'"),
+		Entry("invalid URL pattern",
+			"    - { if: { url: '(' }, then: [ { click: x } ] }\n",
+			"4:20: tab 'Invalid', step 1: unable to compile '(' as 'url' value of a condition: error parsing regexp: missing closing ): `(`"),
+		Entry("no branches",
+			"    - { if: { text: a } }\n",
+			"4:7: tab 'Invalid', step 1: either then or else must have steps"),
+		Entry("unknown key",
+			"    - { if: { text: a }, then: [ { click: x } ], otherwise: [ { click: y } ] }\n",
+			"4:50: tab 'Invalid', step 1: a step must have exactly one action, but has 'if' and 'otherwise'"),
+		Entry("invalid step in a branch",
+			"    - { if: { text: a }, then: [ { click: '' } ] }\n",
+			"4:34: tab 'Invalid', step 1: value must not be empty"),
+	)
+})
+
+var _ = Describe("Unless", func() {
+	It("skips a step if the condition holds", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Grafana
+  script:
+    - go: https://grafana.example.com
+    - click: css:#login
+      unless:
+        url: ^https://grafana\.example\.com/d/
+      timeout: 5s
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Steps).To(HaveLen(2))
+		Expect(tabs[0].Steps[1]).To(BeAssignableToTypeOf(&script.If{}))
+
+		unless := tabs[0].Steps[1].(*script.If)
+		Expect(unless.Condition.String()).To(Equal(`the URL matches '^https://grafana\.example\.com/d/'`))
+		Expect(unless.Then).To(BeEmpty())
+		Expect(unless.Else).To(HaveLen(1))
+		Expect(unless.Else[0].String()).To(Equal("click the element addressed by css '#login' within 5s"))
+	})
+
+	It("skips all steps of a snippet", func() {
+		tabs, err := script.Parse([]byte(`
+snippets:
+  login:
+    - type: { selector: "css:#user", value: jdoe }
+    - click: css:#submit
+tabs:
+  - name: Grafana
+    script:
+      - go: https://grafana.example.com
+      - use: login
+        unless:
+          exists: css:.dashboard
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Steps).To(HaveLen(2))
+
+		unless := tabs[0].Steps[1].(*script.If)
+		Expect(unless.Condition.String()).To(Equal("the element addressed by css '.dashboard' exists"))
+		Expect(unless.Else).To(HaveLen(2))
+	})
+
+	It("rejects an invalid condition", func() {
+		_, err := script.Parse([]byte(`
+- name: Invalid
+  script:
+    - click: css:#login
+      unless: logged in
+`))
+		Expect(err).To(MatchError("5:15: tab 'Invalid', step 1: unable to parse 'logged in' as condition; expecting one of 'exists', 'url' or 'text'"))
+	})
+})
@@ -0,0 +1,124 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Viewport overrides the size of the page. Zero values keep the size of the window or the display's scale factor.
+type Viewport struct {
+	Width             int64   `yaml:"width"`
+	Height            int64   `yaml:"height"`
+	DeviceScaleFactor float64 `yaml:"deviceScaleFactor"`
+}
+
+func (v Viewport) IsZero() bool {
+	return v == Viewport{}
+}
+
+func (v Viewport) Validate() error {
+	if v.Width < 0 || v.Height < 0 {
+		return errors.New("width and height of the viewport must not be negative")
+	}
+
+	if v.DeviceScaleFactor < 0 {
+		return errors.New("device scale factor must not be negative")
+	}
+
+	return nil
+}
+
+// ColorScheme is the preferred color scheme a tab reports to the page.
+type ColorScheme string
+
+const (
+	ColorSchemeDefault ColorScheme = ""
+	ColorSchemeLight   ColorScheme = "light"
+	ColorSchemeDark    ColorScheme = "dark"
+)
+
+func (c ColorScheme) Validate() error {
+	switch c {
+	case ColorSchemeDefault, ColorSchemeLight, ColorSchemeDark:
+		return nil
+	default:
+		return fmt.Errorf("'%v' is not a known color scheme", string(c))
+	}
+}
+
+// Emulation applies the zoom, viewport, user agent and color scheme of the
+// tab. The settings last until the tab is closed, but are meant to be
+// applied again whenever the steps of the tab run again.
+func (n *Tab) Emulation() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if n.UserAgent != "" {
+			if err := emulation.SetUserAgentOverride(n.UserAgent).Do(ctx); err != nil {
+				return fmt.Errorf("could not set user agent: %w", err)
+			}
+		}
+
+		if n.ColorScheme != ColorSchemeDefault {
+			err := emulation.SetEmulatedMedia().
+				WithFeatures([]*emulation.MediaFeature{{Name: "prefers-color-scheme", Value: string(n.ColorScheme)}}).
+				Do(ctx)
+
+			if err != nil {
+				return fmt.Errorf("could not set color scheme: %w", err)
+			}
+		}
+
+		if n.Zoom == 0 && n.Viewport.IsZero() {
+			return nil
+		}
+
+		if err := n.overrideDeviceMetrics(ctx); err != nil {
+			return fmt.Errorf("could not set viewport: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// overrideDeviceMetrics zooms by rendering a viewport that is smaller than
+// the window by the zoom factor, scaled up to the size of the window.
+func (n *Tab) overrideDeviceMetrics(ctx context.Context) error {
+	viewport := n.Viewport
+
+	if n.Zoom == 0 || n.Zoom == 1 {
+		return emulation.SetDeviceMetricsOverride(viewport.Width, viewport.Height, viewport.DeviceScaleFactor, false).Do(ctx)
+	}
+
+	if viewport.Width == 0 || viewport.Height == 0 {
+		// measure the window without a previous override
+		if err := emulation.ClearDeviceMetricsOverride().Do(ctx); err != nil {
+			return err
+		}
+
+		_, _, _, window, _, _, err := page.GetLayoutMetrics().Do(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if viewport.Width == 0 {
+			viewport.Width = window.ClientWidth
+		}
+
+		if viewport.Height == 0 {
+			viewport.Height = window.ClientHeight
+		}
+	}
+
+	return emulation.SetDeviceMetricsOverride(
+		int64(math.Round(float64(viewport.Width)/n.Zoom)),
+		int64(math.Round(float64(viewport.Height)/n.Zoom)),
+		viewport.DeviceScaleFactor,
+		false,
+	).WithScale(n.Zoom).Do(ctx)
+}
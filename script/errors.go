@@ -0,0 +1,141 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem with a script, together with where it was found.
+type Error struct {
+	// File is empty if the script was not read from a file.
+	File string
+	// IncludedFrom lists the files that (transitively) include File, closest first.
+	IncludedFrom []string
+	Line         int
+	Column       int
+	// Tab is the name of the tab, if the error is within one.
+	Tab string
+	// Step is the index of the step in the tab, starting at 1, or zero if the error is not within a step.
+	Step int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+
+	if len(e.IncludedFrom) > 0 {
+		var from []string
+
+		for _, file := range e.IncludedFrom {
+			if file == "" {
+				file = "STDIN"
+			}
+
+			from = append(from, file)
+		}
+
+		b.WriteString(" (included from ")
+		b.WriteString(strings.Join(from, ", included from "))
+		b.WriteString("):")
+	}
+
+	if e.Tab != "" {
+		fmt.Fprintf(&b, " tab '%v'", e.Tab)
+
+		if e.Step > 0 {
+			fmt.Fprintf(&b, ", step %d", e.Step)
+		}
+
+		b.WriteString(":")
+	}
+
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors are all problems found in a script.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// ErrorAt creates an error positioned at the node, e.g. for a StepDecoder. The remaining context is added by the parser.
+func ErrorAt(n *yaml.Node, format string, args ...interface{}) *Error {
+	return &Error{
+		Line:   n.Line,
+		Column: n.Column,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
+
+// location is where in the script the parser currently is.
+type location struct {
+	file         string
+	includedFrom []string
+	tab          string
+	step         int
+}
+
+// wrap adds the location to err. Errors that do not know their position yet are placed at n.
+func (l location) wrap(n *yaml.Node, err error) *Error {
+	var e *Error
+
+	if !errors.As(err, &e) {
+		e = &Error{Err: err}
+	}
+
+	if e.Line == 0 && n != nil {
+		e.Line = n.Line
+		e.Column = n.Column
+	}
+
+	if e.File == "" {
+		e.File = l.file
+		e.IncludedFrom = l.includedFrom
+	}
+
+	if e.Tab == "" {
+		e.Tab = l.tab
+		e.Step = l.step
+	}
+
+	return e
+}
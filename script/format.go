@@ -0,0 +1,215 @@
+package script
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the markup language a script is written in.
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+func (f Format) Validate() error {
+	switch f {
+	case FormatYAML, FormatJSON, FormatTOML:
+		return nil
+	default:
+		return fmt.Errorf("'%v' is not a known format", string(f))
+	}
+}
+
+// DetectFormat tells the format of a script from the extension of its name
+// (a path or a URL), or from its content if the extension is not conclusive.
+func DetectFormat(name string, markup []byte) Format {
+	// strip query and fragment of URLs
+	name, _, _ = strings.Cut(name, "?")
+	name, _, _ = strings.Cut(name, "#")
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yml", ".yaml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+
+	return detectFormatFromContent(markup)
+}
+
+// FormatOfMediaType tells the format of a script by the media type it was served with, if it is conclusive.
+func FormatOfMediaType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML, true
+	case "application/json", "text/json":
+		return FormatJSON, true
+	case "application/toml", "text/toml", "text/x-toml":
+		return FormatTOML, true
+	default:
+		return "", false
+	}
+}
+
+// TOML tables and key/value pairs are not valid at the top of a script in YAML
+var tomlLine = regexp.MustCompile(`^(\[\[?\s*[A-Za-z0-9_."'-]+\s*\]\]?|[A-Za-z0-9_"'-]+\s*=)`)
+
+func detectFormatFromContent(markup []byte) Format {
+	scanner := bufio.NewScanner(bytes.NewReader(markup))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return FormatJSON
+		case tomlLine.MatchString(line):
+			return FormatTOML
+		default:
+			return FormatYAML
+		}
+	}
+
+	return FormatYAML
+}
+
+// decode reads markup in the given format into a YAML document node. JSON is
+// read as YAML, which keeps the positions of its values; the positions of
+// TOML documents are looked up in the markup afterwards.
+func decode(markup []byte, format Format) (*yaml.Node, error) {
+	var root yaml.Node
+
+	if format != FormatTOML {
+		err := yaml.Unmarshal(markup, &root)
+		return &root, err
+	}
+
+	var document map[string]interface{}
+
+	if err := toml.Unmarshal(markup, &document); err != nil {
+		var parseErr toml.ParseError
+
+		// the line of the position may be past the one of its offset
+		if errors.As(err, &parseErr) {
+			line, column := tomlPositions(markup).position(parseErr.Position.Start)
+			return nil, &Error{Line: line, Column: column, Err: errors.New(parseErr.Message)}
+		}
+
+		return nil, err
+	}
+
+	var content yaml.Node
+
+	if err := content.Encode(document); err != nil {
+		return nil, err
+	}
+
+	positions := tomlPositions(markup)
+	positions.place(&content, 0, 0)
+
+	root.Kind = yaml.DocumentNode
+	root.Content = []*yaml.Node{&content}
+
+	return &root, nil
+}
+
+// tomlPositions is the markup of a TOML document, in which the keys of the
+// decoded nodes are looked up, as the TOML decoder does not tell where they are.
+type tomlPositions []byte
+
+// place gives n, which starts at offset at, and its content the positions of
+// their keys, searching from offset from on. Keys are expected in the order of
+// the elements of a list, so the offset of the last key found is returned.
+func (t tomlPositions) place(n *yaml.Node, from int, at int) int {
+	n.Line, n.Column = t.position(at)
+	last := at
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			keyAt, valueAt, found := t.find(key.Value, from)
+
+			if !found {
+				key.Line, key.Column = n.Line, n.Column
+				last = max(last, t.place(value, from, at))
+				continue
+			}
+
+			key.Line, key.Column = t.position(keyAt)
+			last = max(last, keyAt, t.place(value, keyAt, valueAt))
+		}
+	case yaml.SequenceNode:
+		next := from
+
+		for _, element := range n.Content {
+			end := t.place(element, next, at)
+
+			// a table in a list starts with its first key
+			for i := 0; element.Kind == yaml.MappingNode && i < len(element.Content); i += 2 {
+				if key := element.Content[i]; i == 0 || key.Line < element.Line || key.Line == element.Line && key.Column < element.Column {
+					element.Line, element.Column = key.Line, key.Column
+				}
+			}
+
+			if end > next {
+				next = end + 1
+			}
+
+			last = max(last, end)
+		}
+	}
+
+	return last
+}
+
+// find looks up a key from offset on, either before '=' or in a table header,
+// and returns its offset and the offset of its value.
+func (t tomlPositions) find(key string, from int) (int, int, bool) {
+	pattern := regexp.MustCompile(`(?m)(?:^|[\s{,.\[])(["']?` + regexp.QuoteMeta(key) + `["']?)[ \t]*(=[ \t]*|\.|\])`)
+	match := pattern.FindSubmatchIndex(t[from:])
+
+	if match == nil {
+		return 0, 0, false
+	}
+
+	keyAt := from + match[2]
+
+	if t[from+match[4]] == '=' {
+		return keyAt, from + match[5], true
+	}
+
+	return keyAt, keyAt, true
+}
+
+// position returns the line and column of the offset, both starting at 1.
+func (t tomlPositions) position(offset int) (int, int) {
+	before := t[:min(offset, len(t))]
+	line := bytes.Count(before, []byte("\n")) + 1
+
+	return line, offset - bytes.LastIndexByte(before, '\n')
+}
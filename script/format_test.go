@@ -0,0 +1,147 @@
+package script_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Format", func() {
+	DescribeTable("detection",
+		func(name string, markup string, expected script.Format) {
+			Expect(script.DetectFormat(name, []byte(markup))).To(Equal(expected))
+		},
+		Entry("YAML by extension", "kiosk.yml", `{}`, script.FormatYAML),
+		Entry("long YAML extension", "kiosk.YAML", ``, script.FormatYAML),
+		Entry("JSON by extension", "kiosk.json", ``, script.FormatJSON),
+		Entry("TOML by extension", "kiosk.toml", ``, script.FormatTOML),
+		Entry("extension of a URL", "https://example.com/kiosk.toml?v=2", ``, script.FormatTOML),
+		Entry("YAML list", "", "# tabs\n- name: foo\n", script.FormatYAML),
+		Entry("YAML map", "", "tabs:\n  - name: foo\n", script.FormatYAML),
+		Entry("JSON object", "", "\n  { \"tabs\": [] }", script.FormatJSON),
+		Entry("TOML table array", "", "# tabs\n\n[[tabs]]\nname = 'foo'\n", script.FormatTOML),
+		Entry("TOML key", "", "include = 'common.toml'\n", script.FormatTOML),
+		Entry("empty", "", "", script.FormatYAML),
+	)
+
+	DescribeTable("media type",
+		func(contentType string, expected script.Format, conclusive bool) {
+			format, ok := script.FormatOfMediaType(contentType)
+			Expect(ok).To(Equal(conclusive))
+			Expect(format).To(Equal(expected))
+		},
+		Entry("YAML", "application/yaml", script.FormatYAML, true),
+		Entry("JSON with charset", "application/json; charset=utf-8", script.FormatJSON, true),
+		Entry("TOML", "application/toml", script.FormatTOML, true),
+		Entry("plain text", "text/plain", script.Format(""), false),
+		Entry("none", "", script.Format(""), false),
+	)
+
+	Context("JSON", func() {
+		It("parses", func() {
+			tabs, err := script.NewParser().WithPath("kiosk.json").Parse([]byte(`{
+  "vars": { "host": "example.com" },
+  "tabs": [
+    { "name": "com", "script": [ { "go": "https://${host}" }, { "click": "//a" } ] }
+  ]
+}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs).To(HaveLen(1))
+			Expect(tabs[0].Name).To(Equal("com"))
+			Expect(tabs[0].URL()).To(Equal("https://example.com"))
+			Expect(tabs[0].Steps).To(HaveLen(2))
+		})
+
+		It("reports positions", func() {
+			_, err := script.NewParser().WithPath("kiosk.json").Parse([]byte(`[
+  { "name": "com", "script": [ { "go": 42 } ] }
+]`))
+			Expect(err).To(MatchError("kiosk.json:2:40: tab 'com', step 1: unable to parse '42' as value of a Go step"))
+		})
+	})
+
+	Context("TOML", func() {
+		It("parses", func() {
+			tabs, err := script.NewParser().WithPath("kiosk.toml").Parse([]byte(`
+[vars]
+host = "example.com"
+
+[[tabs]]
+name = "com"
+onError = "skip"
+
+  [[tabs.script]]
+  go = "https://${host}"
+
+  [[tabs.script]]
+  type = { selector = "css:#q", value = "kiosk" }
+
+  [[tabs.script]]
+  click = "//button"
+  timeout = "5s"
+
+[[tabs]]
+name = "org"
+script = [ { go = "https://example.org" } ]
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs).To(HaveLen(2))
+			Expect(tabs[0].Name).To(Equal("com"))
+			Expect(tabs[0].OnError).To(Equal(script.OnErrorSkip))
+			Expect(tabs[0].URL()).To(Equal("https://example.com"))
+			Expect(tabs[0].Steps).To(HaveLen(3))
+			Expect(tabs[0].Steps[2].String()).To(Equal("click the element addressed by '//button' within 5s"))
+			Expect(tabs[1].URL()).To(Equal("https://example.org"))
+		})
+
+		It("is detected from the content", func() {
+			tabs, err := script.Parse([]byte(`
+[[tabs]]
+name = "org"
+script = [ { go = "https://example.org" } ]
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs).To(HaveLen(1))
+		})
+
+		It("reports problems", func() {
+			_, err := script.NewParser().WithPath("kiosk.toml").Parse([]byte(`
+[[tabs]]
+name = "org"
+script = [ { go = 42 } ]
+`))
+			Expect(err).To(MatchError("kiosk.toml:4:19: tab 'org', step 1: unable to parse '42' as value of a Go step"))
+		})
+
+		It("reports where problems of later tables are", func() {
+			_, err := script.NewParser().WithPath("kiosk.toml").Parse([]byte(`
+[[tabs]]
+name = "org"
+script = [ { go = "https://example.org" } ]
+
+[[tabs]]
+name = "com"
+onError = "ignore"
+
+  [[tabs.script]]
+  go = "https://example.com"
+
+  [[tabs.script]]
+  click = "//button"
+  timeout = "soon"
+`))
+			Expect(err).To(MatchError(And(
+				ContainSubstring("kiosk.toml:8:11: tab 'com': "),
+				ContainSubstring("kiosk.toml:15:13: tab 'com', step 2: "),
+			)))
+		})
+
+		It("reports syntax errors", func() {
+			_, err := script.NewParser().WithPath("kiosk.toml").Parse([]byte(`
+[[tabs]
+name = "org"
+`))
+			Expect(err).To(MatchError(Equal("kiosk.toml:2:7: expected end of table array name delimiter ']', but got '\\n' instead")))
+		})
+	})
+})
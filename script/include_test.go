@@ -0,0 +1,247 @@
+package script_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Snippets", func() {
+	var scrpt []byte
+	var err error
+	var tabs []*script.Tab
+
+	JustBeforeEach(func() {
+		tabs, err = script.Parse(scrpt)
+	})
+
+	Context("used by a tab", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+vars:
+  user: jdoe
+snippets:
+  login-sso:
+    - wait: css:#login
+    - type:
+        selector: css:#user
+        value: ${user}
+    - use: submit
+  submit:
+    - click: css:button[type=submit]
+tabs:
+  - name: Grafana
+    script:
+      - go: https://grafana.example.com
+      - use: login-sso
+      - wait: css:.dashboard
+`)
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("expands the snippets in place", func() {
+			Expect(tabs[0].Steps).To(HaveLen(5))
+			Expect(tabs[0].Steps[1].String()).To(Equal("wait for the element addressed by css '#login'"))
+			Expect(tabs[0].Steps[2].String()).To(Equal("type 'jdoe' into the element addressed by css '#user'"))
+			Expect(tabs[0].Steps[3].String()).To(Equal("click the element addressed by css 'button[type=submit]'"))
+			Expect(tabs[0].Steps[4].String()).To(Equal("wait for the element addressed by css '.dashboard'"))
+		})
+	})
+
+	Context("unknown snippet", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Grafana
+  script:
+    - use: login-sso
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("4:12: tab 'Grafana', step 1: 'login-sso' is not a known snippet"))
+		})
+	})
+
+	Context("recursive snippet", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+snippets:
+  a:
+    - use: b
+  b:
+    - use: a
+tabs:
+  - name: Loop
+    script:
+      - use: a
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("6:12: tab 'Loop', step 1: snippet 'a' uses itself: a -> b -> a"))
+		})
+	})
+
+	Context("invalid step in snippet", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+snippets:
+  broken:
+    - click: ""
+tabs:
+  - name: Broken
+    script:
+      - use: broken
+`)
+		})
+
+		It("points to the step in the snippet", func() {
+			Expect(err).To(MatchError("4:7: tab 'Broken', step 1: value must not be empty"))
+		})
+	})
+})
+
+var _ = Describe("Includes", func() {
+	var dir string
+	var err error
+	var tabs []*script.Tab
+
+	write := func(name string, content string) {
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	parse := func(name string) {
+		content, readErr := os.ReadFile(filepath.Join(dir, name))
+		Expect(readErr).ToNot(HaveOccurred())
+		tabs, err = script.NewParser().WithPath(filepath.Join(dir, name)).Parse(content)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		Expect(os.Mkdir(filepath.Join(dir, "common"), 0755)).To(Succeed())
+	})
+
+	Context("relative to the script", func() {
+		BeforeEach(func() {
+			write("common/sso.yml", `
+vars:
+  user: default
+snippets:
+  login-sso:
+    - type:
+        xpath: user
+        value: ${user}
+tabs:
+  - name: Wiki
+    script:
+      - go: https://wiki.example.com
+      - use: login-sso
+`)
+			write("kiosk.yml", `
+include:
+  - common/sso.yml
+vars:
+  user: lobby
+tabs:
+  - name: Grafana
+    script:
+      - go: https://grafana.example.com
+      - use: login-sso
+`)
+			parse("kiosk.yml")
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("puts included tabs first", func() {
+			Expect(tabs).To(HaveLen(2))
+			Expect(tabs[0].Name).To(Equal("Wiki"))
+			Expect(tabs[1].Name).To(Equal("Grafana"))
+		})
+
+		It("prefers variables of the including script", func() {
+			Expect(tabs[1].Steps[1].String()).To(Equal("type 'lobby' into the element addressed by 'user'"))
+		})
+	})
+
+	Context("recursive", func() {
+		BeforeEach(func() {
+			write("kiosk.yml", `
+include: [ common/a.yml ]
+`)
+			write("common/a.yml", `
+include: [ b.yml ]
+`)
+			write("common/b.yml", `
+include: [ ../kiosk.yml ]
+`)
+			parse("kiosk.yml")
+		})
+
+		It("reports the include chain", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix(filepath.Join(dir, "common/b.yml") + ":2:12: (included from " + filepath.Join(dir, "common/a.yml") + ", included from " + filepath.Join(dir, "kiosk.yml") + "): recursive include of " + filepath.Join(dir, "kiosk.yml")))
+			Expect(err.Error()).To(HaveSuffix(filepath.Join(dir, "common/b.yml") + " -> " + filepath.Join(dir, "kiosk.yml")))
+		})
+	})
+
+	Context("invalid step in an included tab", func() {
+		BeforeEach(func() {
+			write("common/broken.yml", `
+- name: Broken
+  script:
+    - go: ""
+`)
+			write("kiosk.yml", `
+include: [ common/broken.yml ]
+`)
+			parse("kiosk.yml")
+		})
+
+		It("reports the include chain", func() {
+			Expect(err).To(MatchError(filepath.Join(dir, "common/broken.yml") + ":4:7: (included from " + filepath.Join(dir, "kiosk.yml") + "): tab 'Broken', step 1: value must not be empty"))
+		})
+	})
+
+	Context("missing file", func() {
+		BeforeEach(func() {
+			write("kiosk.yml", `
+include: [ missing.yml ]
+`)
+			parse("kiosk.yml")
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("could not include missing.yml"))
+		})
+	})
+
+	Context("duplicate snippet", func() {
+		BeforeEach(func() {
+			write("common/a.yml", `
+snippets:
+  login: [ { click: a } ]
+`)
+			write("kiosk.yml", `
+include: [ common/a.yml ]
+snippets:
+  login: [ { click: b } ]
+`)
+			parse("kiosk.yml")
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HaveSuffix("snippet 'login' is defined more than once"))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Layout shows several pages at once, side by side in a grid of panes. It
+// takes the place of the steps of a tab.
+type Layout struct {
+	// Columns of the grid, which the panes fill row by row. 0 makes the grid
+	// about as wide as it is high.
+	Columns int
+	Panes   []*Pane
+}
+
+// Pane is a part of a layout with steps of its own.
+type Pane struct {
+	Name  string
+	Steps []Step
+	// Reload reloads the page of the pane this often; 0 never reloads it.
+	Reload time.Duration
+	// Health is checked regularly; if it does not hold, the steps run again.
+	Health Condition
+}
+
+// Grid returns the number of columns and rows of the layout.
+func (l *Layout) Grid() (columns, rows int) {
+	columns = l.Columns
+
+	if columns == 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(l.Panes)))))
+	}
+
+	if columns == 0 {
+		return 0, 0
+	}
+
+	return columns, (len(l.Panes) + columns - 1) / columns
+}
+
+func (l *Layout) Validate() error {
+	if len(l.Panes) == 0 {
+		return errors.New("a layout needs at least one pane")
+	}
+
+	if l.Columns < 0 {
+		return errors.New("columns of a layout must not be negative")
+	}
+
+	return nil
+}
+
+// Steps returns the steps of all panes one after another, each telling which
+// pane it is from, e.g. to try them in a single page.
+func (l *Layout) Steps() []Step {
+	var steps []Step
+
+	for _, pane := range l.Panes {
+		for _, step := range pane.Steps {
+			steps = append(steps, paneStep{pane, step})
+		}
+	}
+
+	return steps
+}
+
+// paneStep is a step of a pane, outside of its layout.
+type paneStep struct {
+	pane *Pane
+	Step
+}
+
+func (p paneStep) String() string {
+	return fmt.Sprintf("in %v: %v", p.pane, p.Step)
+}
+
+// URL returns the target of the first Go step, where the pane starts.
+func (p *Pane) URL() string {
+	return firstURL(p.Steps)
+}
+
+func (p *Pane) Actions() []chromedp.Action {
+	var actions []chromedp.Action
+
+	for _, step := range p.Steps {
+		actions = append(actions, step.Action())
+	}
+
+	return actions
+}
+
+func (p *Pane) Validate() error {
+	if p.URL() == "" {
+		return fmt.Errorf("%v needs a 'go' step", p)
+	}
+
+	if p.Reload < 0 {
+		return fmt.Errorf("reload of %v must not be negative", p)
+	}
+
+	return nil
+}
+
+func (p *Pane) String() string {
+	if p.Name == "" {
+		return "a pane"
+	}
+
+	return fmt.Sprintf("pane '%v'", p.Name)
+}
@@ -0,0 +1,142 @@
+package script_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Layout", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: NOC
+  zoom: 0.5
+  layout:
+    columns: 2
+    panes:
+      - name: grafana
+        reload: 5m
+        health:
+          exists: "#dashboard"
+        script:
+          - go: https://grafana.example.com
+          - click: text:Sign in
+      - script:
+          - go: https://status.example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+		Expect(tabs[0].Steps).To(BeEmpty())
+		Expect(tabs[0].URL()).To(Equal("https://grafana.example.com"))
+
+		layout := tabs[0].Layout
+		Expect(layout).ToNot(BeNil())
+		Expect(layout.Columns).To(Equal(2))
+		Expect(layout.Panes).To(HaveLen(2))
+		Expect(layout.Panes[0].Name).To(Equal("grafana"))
+		Expect(layout.Panes[0].Reload).To(Equal(5 * time.Minute))
+		Expect(layout.Panes[0].Health).To(Equal(script.ElementExists{Selector: script.ParseSelector("#dashboard")}))
+		Expect(layout.Panes[0].Steps).To(HaveLen(2))
+		Expect(layout.Panes[1].URL()).To(Equal("https://status.example.com"))
+	})
+
+	DescribeTable("grid",
+		func(columns, panes, expectedColumns, expectedRows int) {
+			layout := &script.Layout{Columns: columns, Panes: make([]*script.Pane, panes)}
+			actualColumns, actualRows := layout.Grid()
+			Expect(actualColumns).To(Equal(expectedColumns))
+			Expect(actualRows).To(Equal(expectedRows))
+		},
+		Entry("single pane", 0, 1, 1, 1),
+		Entry("two panes side by side", 0, 2, 2, 1),
+		Entry("2x2", 0, 4, 2, 2),
+		Entry("five panes", 0, 5, 3, 2),
+		Entry("given columns", 1, 3, 1, 3),
+		Entry("incomplete row", 3, 4, 3, 2),
+	)
+
+	It("describes the steps of all panes", func() {
+		tabs, err := script.Parse([]byte(`
+- name: NOC
+  layout:
+    panes:
+      - name: left
+        script:
+          - go: https://left.example.com
+      - script:
+          - go: https://right.example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		var descriptions []string
+
+		for _, step := range tabs[0].Layout.Steps() {
+			descriptions = append(descriptions, step.String())
+		}
+
+		Expect(descriptions).To(Equal([]string{
+			"in pane 'left': go to https://left.example.com",
+			"in a pane: go to https://right.example.com",
+		}))
+	})
+
+	It("round-trips", func() {
+		tabs, err := script.Parse([]byte(`
+- name: NOC
+  layout:
+    columns: 2
+    panes:
+      - name: grafana
+        reload: 5m
+        health:
+          url: ^https://grafana
+        script:
+          - go: https://grafana.example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+
+		reparsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reparsed).To(Equal(tabs))
+	})
+
+	DescribeTable("rejected",
+		func(layout string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: NOC
+  layout: ` + layout + `
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("layout that is not a map", `grid`, "3:11: tab 'NOC': unable to parse 'grid' as layout"),
+		Entry("without panes", `{ columns: 2 }`, "3:11: tab 'NOC': a layout needs at least one pane"),
+		Entry("panes that are not a list", `{ panes: grafana }`, "3:20: tab 'NOC': unable to parse 'grafana' as list of panes"),
+		Entry("columns that are not a whole number", `{ columns: 1.5, panes: [] }`, "3:22: tab 'NOC': unable to parse '1.5' as columns of a layout"),
+		Entry("negative columns", `{ columns: -1, panes: [ { script: [ go: https://example.com ] } ] }`, "3:11: tab 'NOC': columns of a layout must not be negative"),
+		Entry("unknown key", `{ rows: 2 }`, "3:13: tab 'NOC': 'rows' is not a known key for a layout"),
+		Entry("pane that is not a map", `{ panes: [ grafana ] }`, "3:22: tab 'NOC': unable to parse 'grafana' as pane"),
+		Entry("pane without a go step", `{ panes: [ { name: empty } ] }`, "3:22: tab 'NOC': pane 'empty' needs a 'go' step"),
+		Entry("unparsable reload", `{ panes: [ { reload: often, script: [ go: https://example.com ] } ] }`, `3:32: tab 'NOC': unable to parse 'often' as reload interval: time: invalid duration "often"`),
+		Entry("unknown health condition", `{ panes: [ { health: { title: x }, script: [ go: https://example.com ] } ] }`, "3:34: tab 'NOC': 'title' is not a known condition"),
+		Entry("unknown pane key", `{ panes: [ { zoom: 2 } ] }`, "3:24: tab 'NOC': 'zoom' is not a known key for a pane"),
+	)
+
+	It("rejects a script next to a layout", func() {
+		_, err := script.Parse([]byte(`
+- name: NOC
+  layout:
+    panes:
+      - script:
+          - go: https://example.com
+  script:
+    - go: https://example.com
+`))
+		Expect(err).To(MatchError("4:5: tab 'NOC': a tab with a layout has no script of its own; its panes have"))
+	})
+})
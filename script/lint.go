@@ -0,0 +1,81 @@
+package script
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xpath"
+)
+
+// Linter is implemented by steps that can be checked more thoroughly than
+// Validate does, without a browser. The checks are run when the parser is
+// asked for them, e.g. when validating a script before deployment.
+type Linter interface {
+	Lint() error
+}
+
+func (g Go) Lint() error {
+	u, err := url.Parse(string(g))
+
+	if err != nil {
+		return fmt.Errorf("'%v' is not a valid URL: %w", string(g), err)
+	}
+
+	if !u.IsAbs() {
+		return fmt.Errorf("'%v' is not an absolute URL", string(g))
+	}
+
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return fmt.Errorf("'%v' has no host", string(g))
+	}
+
+	return nil
+}
+
+func (s Selector) Lint() error {
+	switch s.By {
+	case ByCSS:
+		if _, err := cascadia.Compile(s.Query); err != nil {
+			return fmt.Errorf("'%v' is not a valid CSS selector: %w", s.Query, err)
+		}
+	case ByXPath:
+		return lintXPath(s.Query)
+	case ByDefault:
+		if looksLikeXPath(s.Query) {
+			return lintXPath(s.Query)
+		}
+	}
+
+	return nil
+}
+
+func (i *If) Lint() error {
+	if linter, ok := i.Condition.(Linter); ok {
+		return linter.Lint()
+	}
+
+	return nil
+}
+
+func (t *Timeout) Lint() error {
+	if linter, ok := t.Step.(Linter); ok {
+		return linter.Lint()
+	}
+
+	return nil
+}
+
+func lintXPath(expression string) error {
+	if _, err := xpath.Compile(expression); err != nil {
+		return fmt.Errorf("'%v' is not a valid XPath expression: %w", expression, err)
+	}
+
+	return nil
+}
+
+// looksLikeXPath tells whether chromedp would take the query as XPath rather than CSS or text.
+func looksLikeXPath(query string) bool {
+	return strings.HasPrefix(query, "/") || strings.HasPrefix(query, "./") || strings.HasPrefix(query, "(")
+}
@@ -0,0 +1,121 @@
+package script_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Thorough checks", func() {
+	parse := func(step string) ([]*script.Tab, error) {
+		return script.NewParser().WithThoroughChecks(true).Parse([]byte(`
+- name: Lint
+  script:
+    - ` + step + `
+`))
+	}
+
+	DescribeTable("accepted",
+		func(step string) {
+			tabs, err := parse(step)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs).To(HaveLen(1))
+		},
+		Entry("absolute URL", `go: https://example.com/path?q=1`),
+		Entry("file URL", `go: file:///srv/kiosk/index.html`),
+		Entry("XPath", `click: //button[@id="login"]`),
+		Entry("CSS", `click: css:form > input[type=submit]`),
+		Entry("text", `wait: text:Welcome (back)`),
+		Entry("timeout", `{ wait: css:#ready, timeout: 5s }`),
+		Entry("condition", `{ if: { exists: //div }, then: [ { click: //div } ] }`),
+	)
+
+	DescribeTable("rejected",
+		func(step string, expected string) {
+			tabs, err := parse(step)
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("relative URL",
+			`go: example.com`,
+			"4:7: tab 'Lint', step 1: 'example.com' is not an absolute URL"),
+		Entry("URL without host",
+			`go: https:///index.html`,
+			"4:7: tab 'Lint', step 1: 'https:///index.html' has no host"),
+		Entry("malformed URL",
+			`go: "https://exa mple.com"`,
+			`4:7: tab 'Lint', step 1: 'https://exa mple.com' is not a valid URL: parse "https://exa mple.com": invalid character " " in host name`),
+		Entry("malformed XPath",
+			`click: //button[`,
+			"4:7: tab 'Lint', step 1: '//button[' is not a valid XPath expression: expression must evaluate to a node-set"),
+		Entry("malformed explicit XPath",
+			`click: { selector: "button[", by: xpath }`,
+			"4:7: tab 'Lint', step 1: 'button[' is not a valid XPath expression: expression must evaluate to a node-set"),
+		Entry("malformed CSS",
+			`type: { selector: "input[", by: css, value: x }`,
+			"4:7: tab 'Lint', step 1: 'input[' is not a valid CSS selector: expected identifier, found EOF instead"),
+		Entry("within timeout",
+			`{ wait: "css:#", timeout: 5s }`,
+			"4:7: tab 'Lint', step 1: '#' is not a valid CSS selector: expected name, found EOF instead"),
+		Entry("within condition",
+			`{ if: { exists: "//div[" }, then: [ { click: //div } ] }`,
+			"4:7: tab 'Lint', step 1: '//div[' is not a valid XPath expression: expression must evaluate to a node-set"),
+		Entry("within branch",
+			`{ if: { text: x }, then: [ { go: example.com } ] }`,
+			"4:34: tab 'Lint', step 1: 'example.com' is not an absolute URL"),
+	)
+
+	It("is off by default", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Lenient
+  script:
+    - go: example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Schema", func() {
+	var schema map[string]any
+
+	BeforeEach(func() {
+		raw, err := script.Schema()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(raw, &schema)).To(Succeed())
+	})
+
+	It("has an id", func() {
+		Expect(schema).To(HaveKeyWithValue("$id", script.SchemaID))
+	})
+
+	It("describes all steps", func() {
+		var actions []string
+
+		for _, step := range schema["$defs"].(map[string]any)["step"].(map[string]any)["oneOf"].([]any) {
+			actions = append(actions, step.(map[string]any)["required"].([]any)[0].(string))
+		}
+
+		Expect(actions).To(ContainElements("if", "go", "wait", "click", "type", "use"))
+	})
+
+	It("allows only 'unless' with 'use'", func() {
+		for _, step := range schema["$defs"].(map[string]any)["step"].(map[string]any)["oneOf"].([]any) {
+			if step.(map[string]any)["required"].([]any)[0] == "use" {
+				Expect(step).To(HaveKeyWithValue("properties", And(HaveKey("unless"), Not(HaveKey("timeout")))))
+			}
+		}
+	})
+
+	It("knows the strategies, including the one written for the default", func() {
+		strategy := schema["$defs"].(map[string]any)["strategy"].(map[string]any)
+		Expect(strategy).To(HaveKeyWithValue("enum", ConsistOf("", "css", "xpath", "id", "text", "search")))
+	})
+
+	It("knows the error policies", func() {
+		tab := schema["$defs"].(map[string]any)["tab"].(map[string]any)
+		Expect(tab).To(HaveKeyWithValue("properties", HaveKeyWithValue("onError", HaveKeyWithValue("enum", ConsistOf("abort", "skip", "placeholder", "retry")))))
+	})
+})
@@ -0,0 +1,618 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal writes tabs as canonical YAML, which Parse reads into the same tabs
+// again. Variables, includes and snippets have been resolved by the time
+// tabs are parsed, so they are not part of the result. Secrets are written
+// the way they were referenced, which includes literal ones.
+func Marshal(tabs []*Tab) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if tabs == nil {
+		tabs = []*Tab{}
+	}
+
+	if err := encoder.Encode(tabs); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MarshalScreens writes screens as canonical YAML, which ParseScreens reads
+// into the same screens again. The tabs of the default screen are written
+// like Marshal does, unless there are other screens.
+func MarshalScreens(screens []*Screen) ([]byte, error) {
+	if len(screens) == 0 || len(screens) == 1 && screens[0].IsDefault() && screens[0].isPlain() {
+		var tabs []*Tab
+
+		if len(screens) == 1 {
+			tabs = screens[0].Tabs
+		}
+
+		return Marshal(tabs)
+	}
+
+	root := mapping()
+
+	if screens[0].IsDefault() {
+		tabs, err := marshalTabs(screens[0].Tabs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		root.Content = append(root.Content, scalar("tabs"), tabs)
+		screens = screens[1:]
+	}
+
+	sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+	for _, screen := range screens {
+		n, err := screen.node()
+
+		if err != nil {
+			return nil, err
+		}
+
+		sequence.Content = append(sequence.Content, n)
+	}
+
+	root.Content = append(root.Content, scalar("screens"), sequence)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Screen) node() (*yaml.Node, error) {
+	screen := mapping(scalar("name"), scalar(s.Name))
+
+	if s.Position != (Position{}) {
+		screen.Content = append(screen.Content, scalar("position"), mapping(
+			scalar("x"), number(float64(s.Position.X)),
+			scalar("y"), number(float64(s.Position.Y)),
+		))
+	}
+
+	if s.Size != (Size{}) {
+		screen.Content = append(screen.Content, scalar("size"), mapping(
+			scalar("width"), number(float64(s.Size.Width)),
+			scalar("height"), number(float64(s.Size.Height)),
+		))
+	}
+
+	if s.Interval != 0 {
+		screen.Content = append(screen.Content, scalar("interval"), scalar(s.Interval.String()))
+	}
+
+	tabs, err := marshalTabs(s.Tabs)
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", s, err)
+	}
+
+	screen.Content = append(screen.Content, scalar("tabs"), tabs)
+
+	return screen, nil
+}
+
+func marshalTabs(tabs []*Tab) (*yaml.Node, error) {
+	sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+	for _, tab := range tabs {
+		n, err := marshalNode(tab)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sequence.Content = append(sequence.Content, n)
+	}
+
+	return sequence, nil
+}
+
+func (n *Tab) MarshalYAML() (interface{}, error) {
+	tab := mapping()
+
+	if n.Name != "" {
+		tab.Content = append(tab.Content, scalar("name"), scalar(n.Name))
+	}
+
+	if n.OnError != "" {
+		tab.Content = append(tab.Content, scalar("onError"), scalar(string(n.OnError)))
+	}
+
+	if n.Zoom != 0 {
+		tab.Content = append(tab.Content, scalar("zoom"), number(n.Zoom))
+	}
+
+	if !n.Viewport.IsZero() {
+		viewport := mapping()
+
+		if n.Viewport.Width != 0 {
+			viewport.Content = append(viewport.Content, scalar("width"), number(float64(n.Viewport.Width)))
+		}
+
+		if n.Viewport.Height != 0 {
+			viewport.Content = append(viewport.Content, scalar("height"), number(float64(n.Viewport.Height)))
+		}
+
+		if n.Viewport.DeviceScaleFactor != 0 {
+			viewport.Content = append(viewport.Content, scalar("deviceScaleFactor"), number(n.Viewport.DeviceScaleFactor))
+		}
+
+		tab.Content = append(tab.Content, scalar("viewport"), viewport)
+	}
+
+	if n.UserAgent != "" {
+		tab.Content = append(tab.Content, scalar("userAgent"), scalar(n.UserAgent))
+	}
+
+	if n.ColorScheme != ColorSchemeDefault {
+		tab.Content = append(tab.Content, scalar("colorScheme"), scalar(string(n.ColorScheme)))
+	}
+
+	if len(n.Headers) > 0 {
+		tab.Content = append(tab.Content, scalar("headers"), secrets(n.Headers))
+	}
+
+	if len(n.Cookies) > 0 {
+		cookies := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+		for _, c := range n.Cookies {
+			cookies.Content = append(cookies.Content, c.node())
+		}
+
+		tab.Content = append(tab.Content, scalar("cookies"), cookies)
+	}
+
+	if len(n.LocalStorage) > 0 {
+		tab.Content = append(tab.Content, scalar("localStorage"), secrets(n.LocalStorage))
+	}
+
+	if len(n.SessionStorage) > 0 {
+		tab.Content = append(tab.Content, scalar("sessionStorage"), secrets(n.SessionStorage))
+	}
+
+	if n.Auth != nil {
+		tab.Content = append(tab.Content, scalar("auth"), n.Auth.node())
+	}
+
+	if len(n.Block) > 0 {
+		block := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+		for _, b := range n.Block {
+			block.Content = append(block.Content, scalar(b.Pattern))
+		}
+
+		tab.Content = append(tab.Content, scalar("block"), block)
+	}
+
+	if len(n.Rewrite) > 0 {
+		rewrite := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+		for _, r := range n.Rewrite {
+			rewrite.Content = append(rewrite.Content, mapping(scalar("from"), scalar(r.From), scalar("to"), scalar(r.To)))
+		}
+
+		tab.Content = append(tab.Content, scalar("rewrite"), rewrite)
+	}
+
+	if n.Announcement != nil {
+		tab.Content = append(tab.Content, scalar("announce"), n.Announcement.node())
+
+		return tab, nil
+	}
+
+	if n.Media != nil {
+		tab.Content = append(tab.Content, scalar(string(n.Media.Kind)), n.Media.node())
+
+		return tab, nil
+	}
+
+	if n.Layout != nil {
+		layout, err := n.Layout.node()
+
+		if err != nil {
+			return nil, fmt.Errorf("tab '%v': %w", n.Name, err)
+		}
+
+		tab.Content = append(tab.Content, scalar("layout"), layout)
+
+		return tab, nil
+	}
+
+	steps, err := marshalSteps(n.Steps)
+
+	if err != nil {
+		return nil, fmt.Errorf("tab '%v': %w", n.Name, err)
+	}
+
+	tab.Content = append(tab.Content, scalar("script"), steps)
+
+	return tab, nil
+}
+
+func (g Go) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("go"), scalar(string(g))), nil
+}
+
+func (w Wait) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("wait"), w.Selector.node()), nil
+}
+
+func (c Click) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("click"), c.Selector.node()), nil
+}
+
+func (t *Type) MarshalYAML() (interface{}, error) {
+	value := mapping()
+
+	if t.By == ByDefault && ParseSelector(t.Query).By != ByDefault {
+		value.Content = append(value.Content, scalar("selector"), scalar(t.Query), scalar("by"), scalar(""))
+	} else {
+		value.Content = append(value.Content, scalar("selector"), t.Selector.node())
+	}
+
+	if t.Secret.IsZero() {
+		value.Content = append(value.Content, scalar("value"), scalar(t.Value))
+	} else {
+		value.Content = append(value.Content, scalar("secret"), t.Secret.node())
+	}
+
+	return mapping(scalar("type"), value), nil
+}
+
+func (t *Timeout) MarshalYAML() (interface{}, error) {
+	step, err := marshalStep(t.Step)
+
+	if err != nil {
+		return nil, err
+	}
+
+	step.Content = append(step.Content, scalar("timeout"), scalar(t.Duration.String()))
+
+	return step, nil
+}
+
+func (i *If) MarshalYAML() (interface{}, error) {
+	condition, err := marshalNode(i.Condition)
+
+	if err != nil {
+		return nil, err
+	}
+
+	step := mapping(scalar("if"), condition)
+
+	for _, branch := range []struct {
+		key   string
+		steps []Step
+	}{{"then", i.Then}, {"else", i.Else}} {
+		if len(branch.steps) == 0 {
+			continue
+		}
+
+		steps, err := marshalSteps(branch.steps)
+
+		if err != nil {
+			return nil, err
+		}
+
+		step.Content = append(step.Content, scalar(branch.key), steps)
+	}
+
+	return step, nil
+}
+
+func (e ElementExists) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("exists"), e.Selector.node()), nil
+}
+
+func (u URLMatches) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("url"), scalar(u.Pattern.String())), nil
+}
+
+func (t TextPresent) MarshalYAML() (interface{}, error) {
+	return mapping(scalar("text"), scalar(string(t))), nil
+}
+
+// node is the shortest form of the selector that is parsed the same way again.
+func (s Selector) node() *yaml.Node {
+	if s.By != ByDefault {
+		return scalar(string(s.By) + ":" + s.Query)
+	}
+
+	if ParseSelector(s.Query).By != ByDefault {
+		// the query looks like it has a prefix
+		return mapping(scalar("selector"), scalar(s.Query), scalar("by"), scalar(""))
+	}
+
+	return scalar(s.Query)
+}
+
+// node writes the secret as it was referenced. Secrets are not interpolated,
+// so a '$' is not escaped.
+func (s Secret) node() *yaml.Node {
+	switch s.source {
+	case secretEnv, secretFile:
+		// a string containing '${' looks like a forgotten interpolation
+		if strings.Contains(s.ref, "${") {
+			return mapping(scalar(string(s.source)), verbatim(s.ref))
+		}
+
+		return verbatim(string(s.source) + ":" + s.ref)
+	case secretEncrypted:
+		return mapping(scalar("encrypted"), verbatim(s.ref), scalar("keyFile"), verbatim(s.keyFile))
+	default:
+		if ParseSecret(s.ref).source != secretLiteral || strings.Contains(s.ref, "${") {
+			return mapping(scalar("value"), verbatim(s.ref))
+		}
+
+		return verbatim(s.ref)
+	}
+}
+
+func (c Cookie) node() *yaml.Node {
+	cookie := mapping(scalar("name"), scalar(c.Name), scalar("value"), c.Value.node())
+
+	if c.Domain != "" {
+		cookie.Content = append(cookie.Content, scalar("domain"), scalar(c.Domain))
+	}
+
+	if c.Path != "" {
+		cookie.Content = append(cookie.Content, scalar("path"), scalar(c.Path))
+	}
+
+	if c.Secure {
+		cookie.Content = append(cookie.Content, scalar("secure"), boolean(true))
+	}
+
+	if c.HTTPOnly {
+		cookie.Content = append(cookie.Content, scalar("httpOnly"), boolean(true))
+	}
+
+	return cookie
+}
+
+func (a *Announcement) node() *yaml.Node {
+	announcement := mapping()
+
+	if a.Markdown != "" {
+		announcement.Content = append(announcement.Content, scalar("markdown"), literal(a.Markdown))
+	}
+
+	if a.HTML != "" {
+		announcement.Content = append(announcement.Content, scalar("html"), literal(a.HTML))
+	}
+
+	if a.Data != "" {
+		announcement.Content = append(announcement.Content, scalar("data"), scalar(a.Data))
+	}
+
+	if a.Refresh != 0 {
+		announcement.Content = append(announcement.Content, scalar("refresh"), scalar(a.Refresh.String()))
+	}
+
+	return announcement
+}
+
+func (m *Media) node() *yaml.Node {
+	if m.Duration == 0 {
+		return scalar(m.Path)
+	}
+
+	return mapping(scalar("path"), scalar(m.Path), scalar("duration"), scalar(m.Duration.String()))
+}
+
+func (l *Layout) node() (*yaml.Node, error) {
+	layout := mapping()
+
+	if l.Columns != 0 {
+		layout.Content = append(layout.Content, scalar("columns"), number(float64(l.Columns)))
+	}
+
+	panes := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+	for _, p := range l.Panes {
+		pane := mapping()
+
+		if p.Name != "" {
+			pane.Content = append(pane.Content, scalar("name"), scalar(p.Name))
+		}
+
+		if p.Reload != 0 {
+			pane.Content = append(pane.Content, scalar("reload"), scalar(p.Reload.String()))
+		}
+
+		if p.Health != nil {
+			health, err := marshalNode(p.Health)
+
+			if err != nil {
+				return nil, err
+			}
+
+			pane.Content = append(pane.Content, scalar("health"), health)
+		}
+
+		steps, err := marshalSteps(p.Steps)
+
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", p, err)
+		}
+
+		pane.Content = append(pane.Content, scalar("script"), steps)
+		panes.Content = append(panes.Content, pane)
+	}
+
+	layout.Content = append(layout.Content, scalar("panes"), panes)
+
+	return layout, nil
+}
+
+func (a *Auth) node() *yaml.Node {
+	auth := mapping()
+
+	if a.Username != "" {
+		auth.Content = append(auth.Content, scalar("username"), scalar(a.Username))
+	}
+
+	if !a.Password.IsZero() {
+		auth.Content = append(auth.Content, scalar("password"), a.Password.node())
+	}
+
+	if a.Origin != "" {
+		auth.Content = append(auth.Content, scalar("origin"), scalar(a.Origin))
+	}
+
+	if !a.Certificate.IsZero() {
+		certificate := mapping()
+
+		if a.Certificate.Issuer != "" {
+			certificate.Content = append(certificate.Content, scalar("issuer"), scalar(a.Certificate.Issuer))
+		}
+
+		if a.Certificate.Subject != "" {
+			certificate.Content = append(certificate.Content, scalar("subject"), scalar(a.Certificate.Subject))
+		}
+
+		auth.Content = append(auth.Content, scalar("certificate"), certificate)
+	}
+
+	return auth
+}
+
+// secrets writes the map sorted by name. Unlike values, names are not
+// interpolated, so they are not escaped.
+func secrets(m map[string]Secret) *yaml.Node {
+	n := mapping()
+
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+		n.Content = append(n.Content, key, m[name].node())
+	}
+
+	return n
+}
+
+func marshalSteps(steps []Step) (*yaml.Node, error) {
+	sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+
+	for _, step := range steps {
+		n, err := marshalStep(step)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sequence.Content = append(sequence.Content, n)
+	}
+
+	return sequence, nil
+}
+
+func marshalStep(step Step) (*yaml.Node, error) {
+	n, err := marshalNode(step)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("step '%v' is not marshalled as a map", step)
+	}
+
+	return n, nil
+}
+
+// marshalNode marshals v, which is expected to implement yaml.Marshaler.
+func marshalNode(v interface{}) (*yaml.Node, error) {
+	marshaler, ok := v.(yaml.Marshaler)
+
+	if !ok {
+		return nil, fmt.Errorf("unable to marshal '%v'", v)
+	}
+
+	marshalled, err := marshaler.MarshalYAML()
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := marshalled.(*yaml.Node)
+
+	if !ok {
+		n = &yaml.Node{}
+
+		if err := n.Encode(marshalled); err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func mapping(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: content}
+}
+
+func number(value float64) *yaml.Node {
+	if value == math.Trunc(value) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(int64(value), 10)}
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(value, 'g', -1, 64)}
+}
+
+func boolean(value bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+}
+
+// literal is a scalar written as a block if it spans several lines.
+func literal(value string) *yaml.Node {
+	n := scalar(value)
+
+	if strings.Contains(value, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+
+	return n
+}
+
+// scalar is a string value, escaped so that Parse does not interpolate it.
+func scalar(value string) *yaml.Node {
+	return verbatim(strings.ReplaceAll(value, "$", "$$"))
+}
+
+// verbatim is a string that is not interpolated when parsed, e.g. a secret.
+func verbatim(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,282 @@
+package script_test
+
+import (
+	"math/rand"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Marshal", func() {
+	It("writes canonical YAML", func() {
+		tabs, err := script.Parse([]byte(`
+vars:
+  host: example.com
+snippets:
+  login:
+    - type: { selector: "css:#user", value: jdoe }
+    - type: { xpath: '//input[@type="password"]', secret: env:PASSWORD }
+tabs:
+  - name: com
+    onError: skip
+    script:
+      - go: https://${host}/?price=$$5
+      - use: login
+      - { click: { selector: Sign in, by: text }, timeout: 5s }
+      - if: { url: "^https://example\\.com/welcome" }
+        then:
+          - wait: id:greeting
+        else:
+          - click: "//a"
+  - name: org
+    script:
+      - go: https://example.org
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal(`- name: com
+  onError: skip
+  script:
+    - go: https://example.com/?price=$$5
+    - type:
+        selector: css:#user
+        value: jdoe
+    - type:
+        selector: //input[@type="password"]
+        secret: env:PASSWORD
+    - click: text:Sign in
+      timeout: 5s
+    - if:
+        url: ^https://example\.com/welcome
+      then:
+        - wait: id:greeting
+      else:
+        - click: //a
+- name: org
+  script:
+    - go: https://example.org
+`))
+	})
+
+	It("writes an empty list without tabs", func() {
+		marshalled, err := script.Marshal(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal("[]\n"))
+	})
+
+	DescribeTable("round-trips values that look special",
+		func(step script.Step) {
+			tabs := []*script.Tab{{Name: "Special", Steps: []script.Step{step}}}
+			marshalled, err := script.Marshal(tabs)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := script.Parse(marshalled)
+			Expect(err).ToNot(HaveOccurred(), string(marshalled))
+			Expect(parsed).To(HaveLen(1))
+			Expect(parsed[0].Steps).To(Equal(tabs[0].Steps), string(marshalled))
+		},
+		Entry("query with a strategy prefix", script.Click{Selector: script.Selector{Query: "css:#foo"}}),
+		Entry("query with a strategy prefix and a strategy", script.Click{Selector: script.Selector{Query: "css:#foo", By: script.ByXPath}}),
+		Entry("type query with a strategy prefix", &script.Type{Selector: script.Selector{Query: "id:foo"}, Value: "bar"}),
+		Entry("variable reference", script.Go("https://example.com/${path}")),
+		Entry("number", &script.Type{Selector: script.Selector{Query: "//input"}, Value: "42"}),
+		Entry("boolean", &script.Type{Selector: script.Selector{Query: "//input"}, Value: "yes"}),
+		Entry("null", &script.Type{Selector: script.Selector{Query: "//input"}, Value: "~"}),
+		Entry("multiple lines", &script.Type{Selector: script.Selector{Query: "//textarea"}, Value: "line 1\nline 2\n"}),
+	)
+
+	It("writes secrets as they were referenced", func() {
+		GinkgoT().Setenv("KIOSK_TEST_PASSWORD", "hunter2")
+
+		tabs, err := script.Parse([]byte(`
+- name: com
+  headers:
+    Authorization: env:KIOSK_TEST_PASSWORD
+  script:
+    - go: https://example.com
+    - type: { xpath: password, secret: env:KIOSK_TEST_PASSWORD }
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(ContainSubstring("Authorization: env:KIOSK_TEST_PASSWORD"))
+		Expect(string(marshalled)).To(ContainSubstring("secret: env:KIOSK_TEST_PASSWORD"))
+		Expect(string(marshalled)).ToNot(ContainSubstring("hunter2"))
+	})
+
+	DescribeTable("indirections",
+		func(markup string, expected []string) {
+			indirections, err := script.NewParser().Indirections([]byte(markup))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(indirections).To(Equal(expected))
+		},
+		Entry("none", `[ { name: com, script: [ go: "https://example.com/?price=$$5" ] } ]`, nil),
+		Entry("reference", `[ { name: com, script: [ go: "https://dash-${hostname}" ] } ]`, []string{"${...}"}),
+		Entry("escaped reference", `[ { name: com, script: [ go: "https://example.com/$${x}" ] } ]`, nil),
+		Entry("secret", `[ { name: com, headers: { X-Key: "pa${x" }, script: [ go: "https://example.com" ] } ]`, nil),
+		Entry("vars", `{ vars: { host: example.com }, tabs: [] }`, []string{"vars"}),
+		Entry("includes and snippets", `{ include: common.yml, snippets: { login: [] }, tabs: [ { name: "${x}" } ] }`, []string{"include", "snippets", "${...}"}),
+	)
+
+	It("round-trips random scripts", func() {
+		for seed := int64(1); seed <= 200; seed++ {
+			random := rand.New(rand.NewSource(seed))
+			tabs := randomTabs(random)
+
+			marshalled, err := script.Marshal(tabs)
+			Expect(err).ToNot(HaveOccurred(), "seed %v", seed)
+
+			parsed, err := script.Parse(marshalled)
+			Expect(err).ToNot(HaveOccurred(), "seed %v:\n%s", seed, marshalled)
+			Expect(parsed).To(Equal(tabs), "seed %v:\n%s", seed, marshalled)
+
+			remarshalled, err := script.Marshal(parsed)
+			Expect(err).ToNot(HaveOccurred(), "seed %v", seed)
+			Expect(string(remarshalled)).To(Equal(string(marshalled)), "seed %v", seed)
+		}
+	})
+})
+
+var randomWords = []string{
+	"foo", "Sign in", "42", "3.14", "true", "no", "null", "~", "-", "- item", "a: b", "#comment", "[list]", "{map}",
+	"'single'", `"double"`, "$", "$$", "${var}", "$${var}", "css:#login", "text:Welcome", "env:HOME", "file:/etc/x",
+	"  padded  ", "line 1\nline 2", "Grüße", "tab\there", "*alias", "&anchor", "!tag", "%percent", "@at", "`tick`",
+}
+
+func randomWord(random *rand.Rand) string {
+	return randomWords[random.Intn(len(randomWords))]
+}
+
+func randomSelector(random *rand.Rand) script.Selector {
+	strategies := []script.Strategy{script.ByDefault, script.ByCSS, script.ByXPath, script.ByID, script.ByText, script.BySearch}
+	return script.Selector{Query: randomWord(random), By: strategies[random.Intn(len(strategies))]}
+}
+
+func randomSecret(random *rand.Rand) script.Secret {
+	switch random.Intn(4) {
+	case 0:
+		return script.ParseSecret("env:" + randomWord(random))
+	case 1:
+		return script.ParseSecret("file:" + randomWord(random))
+	case 2:
+		return script.EncryptedSecret(randomWord(random), randomWord(random))
+	default:
+		return script.ParseSecret("literal " + randomWord(random))
+	}
+}
+
+func randomCondition(random *rand.Rand) script.Condition {
+	switch random.Intn(3) {
+	case 0:
+		return script.ElementExists{Selector: randomSelector(random)}
+	case 1:
+		return script.URLMatches{Pattern: regexp.MustCompile(regexp.QuoteMeta(randomWord(random)) + "$")}
+	default:
+		return script.TextPresent(randomWord(random))
+	}
+}
+
+func randomSteps(random *rand.Rand, depth int) []script.Step {
+	steps := make([]script.Step, 1+random.Intn(4))
+
+	for i := range steps {
+		steps[i] = randomStep(random, depth)
+	}
+
+	return steps
+}
+
+func randomStep(random *rand.Rand, depth int) script.Step {
+	kinds := 5
+
+	if depth > 1 {
+		// no more nested conditions
+		kinds = 4
+	}
+
+	var step script.Step
+
+	switch random.Intn(kinds) {
+	case 0:
+		step = script.Go("https://example.com/" + randomWord(random))
+	case 1:
+		step = script.Wait{Selector: randomSelector(random)}
+	case 2:
+		step = script.Click{Selector: randomSelector(random)}
+	case 3:
+		typeStep := &script.Type{Selector: randomSelector(random)}
+
+		if random.Intn(2) == 0 {
+			typeStep.Value = randomWord(random)
+		} else {
+			typeStep.Secret = randomSecret(random)
+		}
+
+		step = typeStep
+	default:
+		ifStep := &script.If{Condition: randomCondition(random)}
+
+		if random.Intn(3) > 0 {
+			ifStep.Then = randomSteps(random, depth+1)
+		}
+
+		if ifStep.Then == nil || random.Intn(2) == 0 {
+			ifStep.Else = randomSteps(random, depth+1)
+		}
+
+		return ifStep
+	}
+
+	if random.Intn(4) == 0 {
+		step = &script.Timeout{Step: step, Duration: time.Duration(1+random.Intn(100000)) * time.Millisecond}
+	}
+
+	return step
+}
+
+func randomTabs(random *rand.Rand) []*script.Tab {
+	policies := []script.ErrorPolicy{"", script.OnErrorAbort, script.OnErrorSkip, script.OnErrorPlaceholder, script.OnErrorRetry}
+	tabs := make([]*script.Tab, 1+random.Intn(3))
+
+	for i := range tabs {
+		tabs[i] = &script.Tab{
+			Name:    randomWord(random),
+			OnError: policies[random.Intn(len(policies))],
+			Steps:   randomSteps(random, 0),
+		}
+
+		if random.Intn(3) == 0 {
+			tabs[i].Zoom = float64(1+random.Intn(40)) / 8
+			tabs[i].Viewport = script.Viewport{Width: int64(random.Intn(4000)), Height: int64(random.Intn(3000)), DeviceScaleFactor: float64(random.Intn(3)) / 2}
+			tabs[i].UserAgent = randomWord(random)
+			tabs[i].ColorScheme = []script.ColorScheme{script.ColorSchemeDefault, script.ColorSchemeLight, script.ColorSchemeDark}[random.Intn(3)]
+		}
+
+		if random.Intn(3) == 0 {
+			// headers are sent to the origin of the tab only
+			if _, err := url.Parse(tabs[i].URL()); err != nil || !strings.HasPrefix(tabs[i].URL(), "https://") {
+				tabs[i].Steps = append([]script.Step{script.Go("https://example.com/")}, tabs[i].Steps...)
+			}
+
+			tabs[i].Headers = map[string]script.Secret{randomWord(random): randomSecret(random)}
+			tabs[i].Cookies = []script.Cookie{{
+				Name:     randomWord(random),
+				Value:    randomSecret(random),
+				Domain:   randomWord(random),
+				Path:     randomWord(random),
+				Secure:   random.Intn(2) == 0,
+				HTTPOnly: random.Intn(2) == 0,
+			}}
+		}
+	}
+
+	return tabs
+}
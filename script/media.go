@@ -0,0 +1,79 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MediaKind tells how a media tab shows its file.
+type MediaKind string
+
+const (
+	MediaImage  MediaKind = "image"
+	MediaVideo  MediaKind = "video"
+	MediaPDF    MediaKind = "pdf"
+	MediaFolder MediaKind = "folder"
+)
+
+// MediaKinds are the keys of media tabs.
+var MediaKinds = []MediaKind{MediaImage, MediaVideo, MediaPDF, MediaFolder}
+
+// Media is a local file shown by a tab instead of a web page, or a folder of
+// images and videos shown one after another.
+type Media struct {
+	Kind MediaKind
+	// Path as given in the script; relative ones are relative to the script.
+	Path string
+	// Duration is how long a single image or video, or each file of a folder
+	// or page of a PDF is shown. 0 keeps the default.
+	Duration time.Duration
+	// dir is the directory of the script.
+	dir string
+}
+
+// File returns the path of the file or folder.
+func (m *Media) File() string {
+	if filepath.IsAbs(m.Path) || m.dir == "" {
+		return m.Path
+	}
+
+	return filepath.Join(m.dir, m.Path)
+}
+
+func (m *Media) Validate() error {
+	if m.Path == "" {
+		return fmt.Errorf("path of the %v must not be empty", m.Kind)
+	}
+
+	if m.Duration < 0 {
+		return errors.New("duration must not be negative")
+	}
+
+	return nil
+}
+
+// Lint checks that the file or folder exists.
+func (m *Media) Lint() error {
+	info, err := os.Stat(m.File())
+
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() != (m.Kind == MediaFolder) {
+		if info.IsDir() {
+			return fmt.Errorf("'%v' is a folder, not a file", m.Path)
+		}
+
+		return fmt.Errorf("'%v' is a file, not a folder", m.Path)
+	}
+
+	return nil
+}
+
+func (m *Media) String() string {
+	return fmt.Sprintf("%v %v", m.Kind, m.Path)
+}
@@ -0,0 +1,117 @@
+package script_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Media", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Poster
+  image: /srv/posters/spring.png
+- name: Loop
+  video:
+    path: /srv/loop.mp4
+    duration: 30s
+- name: Deck
+  pdf:
+    path: /srv/deck.pdf
+    duration: 20s
+- name: Posters
+  folder:
+    path: /srv/posters
+    duration: 1m
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(4))
+
+		Expect(tabs[0].Media.Kind).To(Equal(script.MediaImage))
+		Expect(tabs[0].Media.File()).To(Equal("/srv/posters/spring.png"))
+		Expect(tabs[1].Media.Kind).To(Equal(script.MediaVideo))
+		Expect(tabs[1].Media.Duration).To(Equal(30 * time.Second))
+		Expect(tabs[2].Media.Kind).To(Equal(script.MediaPDF))
+		Expect(tabs[2].Media.Duration).To(Equal(20 * time.Second))
+		Expect(tabs[3].Media.Kind).To(Equal(script.MediaFolder))
+		Expect(tabs[3].Media.Duration).To(Equal(time.Minute))
+		Expect(tabs[3].Steps).To(BeEmpty())
+	})
+
+	It("resolves relative paths against the script", func() {
+		tabs, err := script.NewParser().WithPath("/etc/kiosk/kiosk.yml").Parse([]byte(`
+- image: posters/spring.png
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Media.Path).To(Equal("posters/spring.png"))
+		Expect(tabs[0].Media.File()).To(Equal("/etc/kiosk/posters/spring.png"))
+	})
+
+	It("round-trips", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Poster
+  image: { path: spring.png, duration: 15s }
+- name: Deck
+  pdf: { path: deck.pdf, duration: 20s }
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+
+		reparsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reparsed).To(Equal(tabs))
+	})
+
+	DescribeTable("rejected",
+		func(media string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Poster
+  ` + media + `
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("empty path", `image: ""`, "3:10: tab 'Poster': path of the image must not be empty"),
+		Entry("path that is not a string", `video: [ a.mp4 ]`, "3:10: tab 'Poster': unable to parse '[a.mp4]' as video"),
+		Entry("unknown key", `folder: { path: posters, shuffle: true }`, "3:28: tab 'Poster': 'shuffle' is not a known key for a folder"),
+		Entry("unparsable duration", `folder: { path: posters, duration: long }`, `3:38: tab 'Poster': unable to parse 'long' as duration: time: invalid duration "long"`),
+		Entry("two kinds", "image: a.png\n  video: a.mp4", "4:3: tab 'Poster': a tab shows only one of 'image', 'video', 'pdf' or 'folder'"),
+		Entry("script as well", "image: a.png\n  script: [ go: https://example.com ]", "3:10: tab 'Poster': a media tab has neither a script nor a layout"),
+	)
+
+	Context("thoroughly", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "spring.png"), []byte("png"), 0o600)).To(Succeed())
+		})
+
+		parse := func(markup string) error {
+			_, err := script.NewParser().WithPath(filepath.Join(dir, "kiosk.yml")).WithThoroughChecks(true).Parse([]byte(markup))
+			return err
+		}
+
+		It("accepts existing files and folders", func() {
+			Expect(parse("- image: spring.png\n- folder: .")).To(Succeed())
+		})
+
+		It("rejects missing files", func() {
+			Expect(parse("- image: summer.png")).To(MatchError(ContainSubstring("summer.png: no such file or directory")))
+		})
+
+		It("rejects a file as folder", func() {
+			Expect(parse("- folder: spring.png")).To(MatchError(ContainSubstring("'spring.png' is a file, not a folder")))
+		})
+
+		It("rejects a folder as file", func() {
+			Expect(parse("- video: .")).To(MatchError(ContainSubstring("'.' is a folder, not a file")))
+		})
+	})
+})
@@ -1,106 +1,2062 @@
 package script
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
+type Parser struct {
+	path      string
+	overrides map[string]string
+	thorough  bool
+	format    Format
+}
+
+func NewParser() *Parser {
+	return &Parser{
+		overrides: make(map[string]string),
+	}
+}
+
+// WithPath sets where the script was read from. It is used in error messages,
+// and includes are resolved relative to it.
+func (p *Parser) WithPath(path string) *Parser {
+	p.path = path
+	return p
+}
+
+// WithVariable sets a variable, taking precedence over the one defined in the script.
+func (p *Parser) WithVariable(name string, value string) *Parser {
+	p.overrides[name] = value
+	return p
+}
+
+// WithFormat sets the format of the script instead of detecting it from its path or content.
+func (p *Parser) WithFormat(format Format) *Parser {
+	p.format = format
+	return p
+}
+
+// WithThoroughChecks enables the checks of steps that implement Linter.
+func (p *Parser) WithThoroughChecks(thorough bool) *Parser {
+	p.thorough = thorough
+	return p
+}
+
 func Parse(markup []byte) ([]*Tab, error) {
+	return NewParser().Parse(markup)
+}
+
+// Parse reads the tabs of a script, those of all screens one after another.
+// If there are problems, the returned error is of type Errors and lists all
+// of them.
+func (p *Parser) Parse(markup []byte) ([]*Tab, error) {
+	screens, err := p.ParseScreens(markup)
+
+	if err != nil {
+		return nil, err
+	}
+
 	var tabs []*Tab
 
-	err := yaml.Unmarshal(markup, &tabs)
+	for _, screen := range screens {
+		tabs = append(tabs, screen.Tabs...)
+	}
+
+	return tabs, nil
+}
+
+// ParseScreens reads the screens of a script with their tabs. The default
+// screen comes first; it is left out if it has no tabs, but other screens do.
+func (p *Parser) ParseScreens(markup []byte) ([]*Screen, error) {
+	src := &source{
+		vars:     make(map[string]definition),
+		snippets: make(map[string]definition),
+	}
+
+	format := p.format
+
+	if format == "" {
+		format = DetectFormat(p.path, markup)
+	}
+
+	src.load(markup, format, location{file: p.path})
+
+	vars := p.variables(src)
+
+	for _, name := range slices.Sorted(maps.Keys(src.snippets)) {
+		def := src.snippets[name]
+
+		for _, err := range vars.interpolateNode(def.node) {
+			src.errs = append(src.errs, def.loc.wrap(nil, err))
+		}
+	}
+
+	s := &scope{
+		snippets: src.snippets,
+		thorough: p.thorough,
+		errs:     src.errs,
+	}
+
+	screens := []*Screen{{}}
+	screensByName := map[string]*Screen{"": screens[0]}
+
+	for _, def := range src.screens {
+		screen := s.parseScreen(def)
+
+		if screen == nil {
+			continue
+		}
+
+		if _, found := screensByName[screen.Name]; found {
+			s.fail(def.loc, mappingValue(def.node, "name"), fmt.Errorf("screen '%v' is defined more than once", screen.Name))
+			continue
+		}
+
+		screens = append(screens, screen)
+		screensByName[screen.Name] = screen
+	}
+
+	// announcements are edited by name
+	announcements := make(map[string]bool)
+
+	for i, def := range src.tabs {
+		s.interpolateTab(vars, def, i)
+
+		tab := s.parseTab(def, i)
+		screen, found := screensByName[def.screen]
+
+		if tab != nil && tab.Announcement != nil {
+			if announcements[tab.Name] {
+				s.fail(tabLocation(def, i), mappingValue(def.node, "announce"), fmt.Errorf("announcement '%v' is defined more than once", tab.Name))
+			}
+
+			announcements[tab.Name] = true
+		}
+
+		if tab != nil && found {
+			screen.Tabs = append(screen.Tabs, tab)
+		}
+	}
+
+	if len(s.errs) > 0 {
+		return nil, s.errs
+	}
+
+	if len(screens) > 1 && len(screens[0].Tabs) == 0 {
+		screens = screens[1:]
+	}
+
+	return screens, nil
+}
+
+// Indirections lists which of 'include', 'vars', 'snippets' and ${...}
+// references the script uses. Parsing resolves them, so that writing the
+// parsed script out again loses them, and puts in what they resolved to on
+// this machine.
+func (p *Parser) Indirections(markup []byte) ([]string, error) {
+	format := p.format
+
+	if format == "" {
+		format = DetectFormat(p.path, markup)
+	}
+
+	root, err := decode(markup, format)
 
 	if err != nil {
 		return nil, err
 	}
 
-	for _, tab := range tabs {
-		for _, rawSteps := range tab.RawSteps {
-			var step Step
+	var indirections []string
 
-			for typ, value := range rawSteps {
-				switch typ {
-				case "go":
-					goStep, ok := value.(string)
-					if !ok {
-						err = fmt.Errorf("unable to parse '%v' as value of a Go step", value)
-					} else {
-						step = Go(goStep)
-					}
-				case "wait":
-					waitStep, ok := value.(string)
-					if !ok {
-						err = fmt.Errorf("unable to parse '%v' as value of a Wait step", value)
-					} else {
-						step = Wait(waitStep)
-					}
-				case "click":
-					clickStep, ok := value.(string)
-					if !ok {
-						err = fmt.Errorf("unable to parse '%v' as value of a Click step", value)
-					} else {
-						step = Click(clickStep)
-					}
-				case "type":
-					typeAttributes, ok := value.(map[interface{}]interface{})
-
-					if !ok {
-						err = fmt.Errorf("unable to parse '%v' as value of a Type step", value)
-					} else {
-						var typeStep Type
-
-						for k, v := range typeAttributes {
-							switch k {
-							case "xpath":
-								tt, ok := v.(string)
-
-								if ok {
-									typeStep.XPath = tt
-								} else {
-									err = fmt.Errorf("unable to convert '%v' as 'xpath' value of a Type step", v)
-								}
-							case "value":
-								tt, ok := v.(string)
-
-								if ok {
-									typeStep.Value = tt
-								} else {
-									err = fmt.Errorf("unable to convert '%v' as 'value' value of a Type step", v)
-								}
-							case "secret":
-								tt, ok := v.(string)
-
-								if ok {
-									typeStep.Secret = tt
-								} else {
-									err = fmt.Errorf("unable to convert '%v' as 'secret' value of a Type step", v)
-								}
-							default:
-								err = fmt.Errorf("'%v' is not a known key for a Type step", k)
-							}
-						}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	if n := resolve(root.Content[0]); n.Kind == yaml.MappingNode {
+		for _, key := range []string{"include", "vars", "snippets"} {
+			if mappingValue(n, key) != nil {
+				indirections = append(indirections, key)
+			}
+		}
+	}
+
+	if hasReferences(root) {
+		indirections = append(indirections, "${...}")
+	}
+
+	return indirections, nil
+}
+
+// Includes lists the paths and URLs of the files the script includes, also
+// through other included files. It reads them to find out.
+func (p *Parser) Includes(markup []byte) ([]string, error) {
+	src := &source{
+		vars:     make(map[string]definition),
+		snippets: make(map[string]definition),
+	}
+
+	format := p.format
+
+	if format == "" {
+		format = DetectFormat(p.path, markup)
+	}
+
+	src.load(markup, format, location{file: p.path})
+
+	if len(src.errs) > 0 {
+		return nil, src.errs
+	}
+
+	return src.includes, nil
+}
+
+// definition is a part of the script and where it comes from.
+type definition struct {
+	node *yaml.Node
+	loc  location
+	// screen is the name of the screen a tab belongs to.
+	screen string
+}
+
+// source is the content of a script and everything it includes.
+type source struct {
+	vars     map[string]definition
+	snippets map[string]definition
+	screens  []definition
+	tabs     []definition
+	// includes are the paths of the included files.
+	includes []string
+	errs     Errors
+}
+
+// load adds the content of a file to the source, after everything it includes.
+func (src *source) load(markup []byte, format Format, loc location) {
+	root, err := decode(markup, format)
+
+	if err != nil {
+		src.errs = append(src.errs, loc.wrap(nil, err))
+		return
+	}
+
+	if len(root.Content) == 0 {
+		return
+	}
+
+	n := resolve(root.Content[0])
+
+	switch {
+	case n.Kind == yaml.SequenceNode:
+		src.addTabs(n, loc, "")
+	case n.Kind == yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			key, value := n.Content[i], resolve(n.Content[i+1])
+
+			switch key.Value {
+			case "include":
+				src.include(value, loc)
+			case "vars":
+				src.addVariables(value, loc)
+			case "snippets":
+				src.addSnippets(value, loc)
+			case "tabs":
+				if isNull(value) {
+					continue
+				}
+
+				if value.Kind != yaml.SequenceNode {
+					src.errs = append(src.errs, loc.wrap(value, fmt.Errorf("unable to parse '%v' as list of tabs", describe(value))))
+					continue
+				}
+
+				src.addTabs(value, loc, "")
+			case "screens":
+				src.addScreens(value, loc)
+			default:
+				src.errs = append(src.errs, loc.wrap(key, fmt.Errorf("'%v' is not a known key for a script", key.Value)))
+			}
+		}
+	case isNull(n):
+	default:
+		src.errs = append(src.errs, loc.wrap(n, errors.New("expecting either a list of tabs or a map with 'tabs'")))
+	}
+}
+
+func (src *source) addTabs(n *yaml.Node, loc location, screen string) {
+	for _, tab := range n.Content {
+		src.tabs = append(src.tabs, definition{node: resolve(tab), loc: loc, screen: screen})
+	}
+}
+
+// addScreens adds the screens, and their tabs to the ones of the script.
+func (src *source) addScreens(n *yaml.Node, loc location) {
+	if isNull(n) {
+		return
+	}
+
+	if n.Kind != yaml.SequenceNode {
+		src.errs = append(src.errs, loc.wrap(n, fmt.Errorf("unable to parse '%v' as list of screens", describe(n))))
+		return
+	}
+
+	for _, screenNode := range n.Content {
+		screenNode = resolve(screenNode)
+
+		if screenNode.Kind != yaml.MappingNode {
+			src.errs = append(src.errs, loc.wrap(screenNode, fmt.Errorf("unable to parse '%v' as screen", describe(screenNode))))
+			continue
+		}
+
+		name, ok := stringValue(mappingValue(screenNode, "name"))
+
+		if !ok || name == "" {
+			src.errs = append(src.errs, loc.wrap(screenNode, errors.New("a screen needs a name")))
+			continue
+		}
+
+		src.screens = append(src.screens, definition{node: screenNode, loc: loc, screen: name})
+
+		tabs := mappingValue(screenNode, "tabs")
+
+		if isNull(tabs) {
+			continue
+		}
+
+		if tabs.Kind != yaml.SequenceNode {
+			src.errs = append(src.errs, loc.wrap(tabs, fmt.Errorf("unable to parse '%v' as list of tabs", describe(tabs))))
+			continue
+		}
 
-						step = &typeStep
+		src.addTabs(tabs, loc, name)
+	}
+}
+
+func (src *source) addVariables(n *yaml.Node, loc location) {
+	if isNull(n) {
+		return
+	}
+
+	if n.Kind != yaml.MappingNode {
+		src.errs = append(src.errs, loc.wrap(n, fmt.Errorf("unable to parse '%v' as variables", describe(n))))
+		return
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		if value.Kind != yaml.ScalarNode {
+			src.errs = append(src.errs, loc.wrap(value, fmt.Errorf("value of variable '%v' must be a scalar", key.Value)))
+			continue
+		}
+
+		// included files come first, so the including one overrides them
+		src.vars[key.Value] = definition{node: value, loc: loc}
+	}
+}
+
+func (src *source) addSnippets(n *yaml.Node, loc location) {
+	if isNull(n) {
+		return
+	}
+
+	if n.Kind != yaml.MappingNode {
+		src.errs = append(src.errs, loc.wrap(n, fmt.Errorf("unable to parse '%v' as snippets", describe(n))))
+		return
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		if _, found := src.snippets[key.Value]; found {
+			src.errs = append(src.errs, loc.wrap(key, fmt.Errorf("snippet '%v' is defined more than once", key.Value)))
+			continue
+		}
+
+		if value.Kind != yaml.SequenceNode && !isNull(value) {
+			src.errs = append(src.errs, loc.wrap(value, fmt.Errorf("unable to parse '%v' as steps of snippet '%v'", describe(value), key.Value)))
+			continue
+		}
+
+		src.snippets[key.Value] = definition{node: value, loc: loc}
+	}
+}
+
+func (src *source) include(n *yaml.Node, loc location) {
+	var includes []*yaml.Node
+
+	switch {
+	case isNull(n):
+	case n.Kind == yaml.SequenceNode:
+		includes = n.Content
+	default:
+		includes = []*yaml.Node{n}
+	}
+
+	for _, include := range includes {
+		name, ok := stringValue(include)
+
+		if !ok {
+			src.errs = append(src.errs, loc.wrap(include, fmt.Errorf("unable to parse '%v' as file to include", describe(include))))
+			continue
+		}
+
+		path, err := resolveInclude(loc.file, name)
+
+		if err != nil {
+			src.errs = append(src.errs, loc.wrap(include, fmt.Errorf("could not include %v: %w", name, err)))
+			continue
+		}
+
+		chain := append([]string{loc.file}, loc.includedFrom...)
+
+		if slices.Contains(chain, path) {
+			outermostFirst := slices.Clone(chain)
+			slices.Reverse(outermostFirst)
+			src.errs = append(src.errs, loc.wrap(include, fmt.Errorf("recursive include of %v: %v", path, strings.Join(append(outermostFirst, path), " -> "))))
+			continue
+		}
+
+		content, err := readInclude(path)
+
+		if err != nil {
+			src.errs = append(src.errs, loc.wrap(include, fmt.Errorf("could not include %v: %w", name, err)))
+			continue
+		}
+
+		src.includes = append(src.includes, path)
+		src.load(content, DetectFormat(path, content), location{file: path, includedFrom: chain})
+	}
+}
+
+// variables collects the variables of the script, overridden by the ones
+// set on the parser. Definitions may refer to ${hostname} and environment
+// variables, but not to each other.
+func (p *Parser) variables(src *source) variables {
+	builtins := newVariables()
+	vars := newVariables()
+
+	for _, name := range slices.Sorted(maps.Keys(src.vars)) {
+		def := src.vars[name]
+		interpolated, err := builtins.interpolate(def.node.Value)
+
+		if err != nil {
+			src.errs = append(src.errs, def.loc.wrap(def.node, fmt.Errorf("variable '%v': %w", name, err)))
+			continue
+		}
+
+		vars[name] = interpolated
+	}
+
+	for name, value := range p.overrides {
+		vars[name] = value
+	}
+
+	return vars
+}
+
+// scope holds what steps may refer to, and collects the problems found.
+type scope struct {
+	snippets map[string]definition
+	thorough bool
+	errs     Errors
+}
+
+func (s *scope) fail(loc location, n *yaml.Node, err error) {
+	s.errs = append(s.errs, loc.wrap(n, err))
+}
+
+// tabLocation is where a tab is, using its name if it has one.
+func tabLocation(def definition, index int) location {
+	loc := def.loc
+	loc.tab = fmt.Sprintf("#%d", index+1)
+
+	if name, ok := stringValue(mappingValue(def.node, "name")); ok && name != "" {
+		loc.tab = name
+	}
+
+	return loc
+}
+
+func (s *scope) interpolateTab(vars variables, def definition, index int) {
+	if def.node.Kind != yaml.MappingNode {
+		return
+	}
+
+	loc := tabLocation(def, index)
+
+	for i := 0; i < len(def.node.Content); i += 2 {
+		key, value := def.node.Content[i], resolve(def.node.Content[i+1])
+
+		if secretKeys[key.Value] {
+			continue
+		}
+
+		// the value of a cookie is a secret, its other settings are not
+		if key.Value == "cookies" && value.Kind == yaml.SequenceNode {
+			for _, cookie := range value.Content {
+				cookie = resolve(cookie)
+
+				if cookie.Kind != yaml.MappingNode {
+					continue
+				}
+
+				for j := 0; j < len(cookie.Content); j += 2 {
+					if cookie.Content[j].Value != "value" {
+						for _, err := range vars.interpolateNode(cookie.Content[j+1]) {
+							s.fail(loc, nil, err)
+						}
 					}
-				default:
-					err = fmt.Errorf("'%v' is not a known step", typ)
 				}
 			}
 
+			continue
+		}
+
+		if key.Value == "script" && value.Kind == yaml.SequenceNode {
+			for j, step := range value.Content {
+				stepLoc := loc
+				stepLoc.step = j + 1
+
+				for _, err := range vars.interpolateNode(step) {
+					s.fail(stepLoc, nil, err)
+				}
+			}
+
+			continue
+		}
+
+		for _, err := range vars.interpolateNode(value) {
+			s.fail(loc, nil, err)
+		}
+	}
+}
+
+// parseScreen decodes the settings of a screen; its tabs are parsed along
+// with all others.
+func (s *scope) parseScreen(def definition) *Screen {
+	n := def.node
+	screen := &Screen{Name: def.screen}
+	failures := len(s.errs)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "name", "tabs":
+		case "position":
+			values, err := decodeWholeNumbers(value, "position", "x", "y")
+
+			if err != nil {
+				s.fail(def.loc, value, err)
+				continue
+			}
+
+			screen.Position = Position{X: values["x"], Y: values["y"]}
+		case "size":
+			values, err := decodeWholeNumbers(value, "size", "width", "height")
+
 			if err != nil {
-				return nil, err
+				s.fail(def.loc, value, err)
+				continue
 			}
 
-			validationError := step.Validate()
+			screen.Size = Size{Width: values["width"], Height: values["height"]}
+		case "interval":
+			tt, ok := stringValue(value)
 
-			if validationError != nil {
-				return nil, validationError
+			if !ok {
+				s.fail(def.loc, value, fmt.Errorf("unable to parse '%v' as interval", describe(value)))
+				continue
+			}
+
+			interval, err := time.ParseDuration(tt)
+
+			if err != nil {
+				s.fail(def.loc, value, fmt.Errorf("unable to parse '%v' as interval: %v", tt, err))
+				continue
 			}
 
-			tab.Steps = append(tab.Steps, step)
+			screen.Interval = interval
+		default:
+			s.fail(def.loc, key, fmt.Errorf("'%v' is not a known key for a screen", key.Value))
 		}
 	}
 
-	return tabs, nil
+	if err := screen.Validate(); err != nil {
+		s.fail(def.loc, n, err)
+	}
+
+	if len(s.errs) > failures {
+		return nil
+	}
+
+	return screen
+}
+
+// decodeWholeNumbers reads a map with some of the given keys, each with a
+// whole number, e.g. the position of a screen.
+func decodeWholeNumbers(n *yaml.Node, what string, keys ...string) (map[string]int64, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as %v", describe(n), what)
+	}
+
+	values := make(map[string]int64)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		if !slices.Contains(keys, key.Value) {
+			return nil, ErrorAt(key, "'%v' is not a known key for a %v", key.Value, what)
+		}
+
+		number, ok := floatValue(value)
+
+		if !ok || number != math.Trunc(number) {
+			return nil, ErrorAt(value, "'%v' of a %v must be a whole number", key.Value, what)
+		}
+
+		values[key.Value] = int64(number)
+	}
+
+	return values, nil
+}
+
+func (s *scope) parseTab(def definition, index int) *Tab {
+	loc := tabLocation(def, index)
+	n := def.node
+
+	if n.Kind != yaml.MappingNode {
+		s.fail(loc, n, fmt.Errorf("unable to parse '%v' as tab", describe(n)))
+		return nil
+	}
+
+	var tab Tab
+	var headersNode, cookiesNode, storageNode, authNode, layoutNode, mediaNode, announceNode *yaml.Node
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "name":
+			name, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as name of a tab", describe(value)))
+				continue
+			}
+
+			tab.Name = name
+		case "onError":
+			policy, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as error policy", describe(value)))
+				continue
+			}
+
+			tab.OnError = ErrorPolicy(policy)
+
+			if err := tab.OnError.Validate(); err != nil {
+				s.fail(loc, value, err)
+			}
+		case "zoom":
+			zoom, ok := floatValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as zoom", describe(value)))
+				continue
+			}
+
+			if zoom <= 0 {
+				s.fail(loc, value, errors.New("zoom must be positive"))
+				continue
+			}
+
+			tab.Zoom = zoom
+		case "viewport":
+			viewport, err := decodeViewport(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			tab.Viewport = viewport
+		case "userAgent":
+			userAgent, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as user agent", describe(value)))
+				continue
+			}
+
+			tab.UserAgent = userAgent
+		case "colorScheme":
+			colorScheme, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as color scheme", describe(value)))
+				continue
+			}
+
+			tab.ColorScheme = ColorScheme(colorScheme)
+
+			if err := tab.ColorScheme.Validate(); err != nil {
+				s.fail(loc, value, err)
+			}
+		case "script":
+			if isNull(value) {
+				continue
+			}
+
+			if value.Kind != yaml.SequenceNode {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as list of steps", describe(value)))
+				continue
+			}
+
+			tab.Steps = s.parseSteps(value, loc, nil)
+		case "headers":
+			headers, err := decodeSecrets(value, "header")
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			tab.Headers = headers
+			headersNode = value
+		case "cookies":
+			cookies, err := decodeCookies(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			cookiesNode = value
+			tab.Cookies = cookies
+		case "localStorage", "sessionStorage":
+			items, err := decodeSecrets(value, key.Value+" item")
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			storageNode = value
+
+			if key.Value == "localStorage" {
+				tab.LocalStorage = items
+			} else {
+				tab.SessionStorage = items
+			}
+		case "auth":
+			auth, err := decodeAuth(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			authNode = value
+			tab.Auth = auth
+		case "block":
+			rules, err := decodeBlockRules(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			tab.Block = rules
+		case "rewrite":
+			rules, err := decodeRewriteRules(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			tab.Rewrite = rules
+		case "layout":
+			layoutNode = value
+			tab.Layout = s.parseLayout(value, loc)
+		case string(MediaImage), string(MediaVideo), string(MediaPDF), string(MediaFolder):
+			if mediaNode != nil {
+				s.fail(loc, key, errors.New("a tab shows only one of 'image', 'video', 'pdf' or 'folder'"))
+				continue
+			}
+
+			mediaNode = value
+			media, err := decodeMedia(MediaKind(key.Value), value, loc)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			if s.thorough {
+				if err := media.Lint(); err != nil {
+					s.fail(loc, value, err)
+					continue
+				}
+			}
+
+			tab.Media = media
+		case "announce":
+			announcement, err := decodeAnnouncement(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			announceNode = value
+			tab.Announcement = announcement
+		default:
+			s.fail(loc, key, fmt.Errorf("'%v' is not a known key for a tab", key.Value))
+		}
+	}
+
+	if layoutNode != nil && len(tab.Steps) > 0 {
+		s.fail(loc, layoutNode, errors.New("a tab with a layout has no script of its own; its panes have"))
+	}
+
+	if mediaNode != nil && (len(tab.Steps) > 0 || layoutNode != nil) {
+		s.fail(loc, mediaNode, errors.New("a media tab has neither a script nor a layout"))
+	}
+
+	if announceNode != nil {
+		if len(tab.Steps) > 0 || layoutNode != nil || mediaNode != nil {
+			s.fail(loc, announceNode, errors.New("an announcement tab has neither a script, a layout nor media"))
+		}
+
+		// the name addresses the announcement when editing it
+		if tab.Name == "" {
+			s.fail(loc, announceNode, errors.New("an announcement needs the tab to have a name"))
+		}
+	}
+
+	if authNode != nil && tab.authOrigin() == "" {
+		s.fail(loc, authNode, errors.New("auth needs an 'origin' if the tab does not go to an absolute URL"))
+	}
+
+	// both need to know where the tab goes, which may be given after them
+	if cookiesNode != nil && tab.URL() == "" && slices.ContainsFunc(tab.Cookies, func(c Cookie) bool { return c.Domain == "" }) {
+		s.fail(loc, cookiesNode, errors.New("cookies need a 'domain' if the tab has no 'go' step"))
+	}
+
+	if headersNode != nil && len(tab.headerOrigins()) == 0 {
+		s.fail(loc, headersNode, fmt.Errorf("headers need the tab to go to an absolute URL first, not '%v'", tab.URL()))
+	}
+
+	if storageNode != nil {
+		if _, err := origin(tab.URL()); err != nil {
+			s.fail(loc, storageNode, err)
+		}
+	}
+
+	return &tab
+}
+
+// decodeMedia accepts the path of the file, or a map with the path and how
+// long it or each of its items is shown.
+func decodeMedia(kind MediaKind, n *yaml.Node, loc location) (*Media, error) {
+	media := &Media{Kind: kind}
+
+	if loc.file != "" && !IsURL(loc.file) {
+		media.dir = filepath.Dir(loc.file)
+	}
+
+	if path, ok := stringValue(n); ok {
+		media.Path = path
+	} else if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			key, value := n.Content[i], resolve(n.Content[i+1])
+
+			switch key.Value {
+			case "path":
+				path, ok := stringValue(value)
+
+				if !ok {
+					return nil, ErrorAt(value, "unable to parse '%v' as path of the %v", describe(value), kind)
+				}
+
+				media.Path = path
+			case "duration":
+				duration, ok := stringValue(value)
+
+				if !ok {
+					return nil, ErrorAt(value, "unable to parse '%v' as duration", describe(value))
+				}
+
+				d, err := time.ParseDuration(duration)
+
+				if err != nil {
+					return nil, ErrorAt(value, "unable to parse '%v' as duration: %v", duration, err)
+				}
+
+				media.Duration = d
+			default:
+				return nil, ErrorAt(key, "'%v' is not a known key for a %v", key.Value, kind)
+			}
+		}
+	} else {
+		return nil, fmt.Errorf("unable to parse '%v' as %v", describe(n), kind)
+	}
+
+	if err := media.Validate(); err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+func decodeAnnouncement(n *yaml.Node) (*Announcement, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("unable to parse '%v' as announcement", describe(n))
+	}
+
+	var announcement Announcement
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "markdown", "html", "data", "refresh":
+		default:
+			return nil, ErrorAt(key, "'%v' is not a known key for an announcement", key.Value)
+		}
+
+		text, ok := stringValue(value)
+
+		if !ok {
+			return nil, ErrorAt(value, "unable to parse '%v' as '%v' of an announcement", describe(value), key.Value)
+		}
+
+		switch key.Value {
+		case "markdown":
+			announcement.Markdown = text
+		case "html":
+			announcement.HTML = text
+		case "data":
+			announcement.Data = text
+		case "refresh":
+			refresh, err := time.ParseDuration(text)
+
+			if err != nil {
+				return nil, ErrorAt(value, "unable to parse '%v' as refresh of an announcement: %v", text, err)
+			}
+
+			announcement.Refresh = refresh
+		}
+	}
+
+	if err := announcement.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &announcement, nil
+}
+
+// parseLayout decodes the columns and panes of a layout.
+func (s *scope) parseLayout(n *yaml.Node, loc location) *Layout {
+	if n.Kind != yaml.MappingNode {
+		s.fail(loc, n, fmt.Errorf("unable to parse '%v' as layout", describe(n)))
+		return nil
+	}
+
+	var layout Layout
+	failures := len(s.errs)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "columns":
+			columns, ok := floatValue(value)
+
+			if !ok || columns != math.Trunc(columns) {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as columns of a layout", describe(value)))
+				continue
+			}
+
+			layout.Columns = int(columns)
+		case "panes":
+			if value.Kind != yaml.SequenceNode {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as list of panes", describe(value)))
+				continue
+			}
+
+			for _, paneNode := range value.Content {
+				if pane := s.parsePane(resolve(paneNode), loc); pane != nil {
+					layout.Panes = append(layout.Panes, pane)
+				}
+			}
+		default:
+			s.fail(loc, key, fmt.Errorf("'%v' is not a known key for a layout", key.Value))
+		}
+	}
+
+	if len(s.errs) > failures {
+		return nil
+	}
+
+	if err := layout.Validate(); err != nil {
+		s.fail(loc, n, err)
+		return nil
+	}
+
+	return &layout
+}
+
+func (s *scope) parsePane(n *yaml.Node, loc location) *Pane {
+	if n.Kind != yaml.MappingNode {
+		s.fail(loc, n, fmt.Errorf("unable to parse '%v' as pane", describe(n)))
+		return nil
+	}
+
+	var pane Pane
+	failures := len(s.errs)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "name":
+			name, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as name of a pane", describe(value)))
+				continue
+			}
+
+			pane.Name = name
+		case "script":
+			if isNull(value) {
+				continue
+			}
+
+			if value.Kind != yaml.SequenceNode {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as list of steps", describe(value)))
+				continue
+			}
+
+			pane.Steps = s.parseSteps(value, loc, nil)
+		case "reload":
+			reload, ok := stringValue(value)
+
+			if !ok {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as reload interval", describe(value)))
+				continue
+			}
+
+			duration, err := time.ParseDuration(reload)
+
+			if err != nil {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as reload interval: %v", reload, err))
+				continue
+			}
+
+			pane.Reload = duration
+		case "health":
+			condition, err := parseCondition(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			pane.Health = condition
+		default:
+			s.fail(loc, key, fmt.Errorf("'%v' is not a known key for a pane", key.Value))
+		}
+	}
+
+	if len(s.errs) > failures {
+		return nil
+	}
+
+	if err := pane.Validate(); err != nil {
+		s.fail(loc, n, err)
+		return nil
+	}
+
+	return &pane
+}
+
+// parseSteps decodes a list of steps, expanding snippets. If loc is not
+// within a step yet, the steps are numbered. using is the chain of snippets
+// currently being expanded.
+func (s *scope) parseSteps(n *yaml.Node, loc location, using []string) []Step {
+	var steps []Step
+
+	for i, stepNode := range n.Content {
+		stepLoc := loc
+
+		if stepLoc.step == 0 {
+			stepLoc.step = i + 1
+		}
+
+		steps = append(steps, s.parseStepNode(resolve(stepNode), stepLoc, using)...)
+	}
+
+	return steps
+}
+
+func (s *scope) parseStepNode(n *yaml.Node, loc location, using []string) []Step {
+	if n.Kind != yaml.MappingNode {
+		s.fail(loc, n, fmt.Errorf("unable to parse '%v' as step", describe(n)))
+		return nil
+	}
+
+	action, modifiers, err := splitStep(n)
+
+	if err != nil {
+		s.fail(loc, n, err)
+		return nil
+	}
+
+	var step Step
+
+	switch action.Value {
+	case "use":
+		for _, mod := range modifiers {
+			if mod.Value != "unless" {
+				s.fail(loc, mod, fmt.Errorf("'%v' cannot be applied to a Use step", mod.Value))
+				return nil
+			}
+		}
+
+		steps := s.expandSnippet(mappingValue(n, "use"), loc, using)
+
+		if len(modifiers) == 0 || len(steps) == 0 {
+			return steps
+		}
+
+		condition, err := parseCondition(mappingValue(n, "unless"))
+
+		if err != nil {
+			s.fail(loc, n, err)
+			return nil
+		}
+
+		return []Step{&If{Condition: condition, Else: steps}}
+	case "if":
+		step = s.parseIf(n, loc, using)
+
+		if step == nil {
+			return nil
+		}
+	default:
+		step, err = parseStep(action, mappingValue(n, action.Value))
+
+		if err != nil {
+			s.fail(loc, n, err)
+			return nil
+		}
+	}
+
+	step, err = applyModifiers(step, n, modifiers)
+
+	if err != nil {
+		s.fail(loc, n, err)
+		return nil
+	}
+
+	if linter, ok := step.(Linter); ok && s.thorough {
+		if err = linter.Lint(); err != nil {
+			s.fail(loc, n, err)
+			return nil
+		}
+	}
+
+	return []Step{step}
+}
+
+// modifiers may accompany the action of any step.
+var modifiers = []string{"timeout", "unless"}
+
+// splitStep separates the one action of a step from its modifiers. The
+// branches 'then' and 'else' belong to an 'if' action.
+func splitStep(n *yaml.Node) (action *yaml.Node, mods []*yaml.Node, err error) {
+	var branch *yaml.Node
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key := n.Content[i]
+
+		if seen[key.Value] {
+			return nil, nil, ErrorAt(key, "'%v' is given more than once", key.Value)
+		}
+
+		seen[key.Value] = true
+
+		switch {
+		case slices.Contains(modifiers, key.Value):
+			mods = append(mods, key)
+		case key.Value == "then" || key.Value == "else":
+			branch = key
+		case action != nil:
+			return nil, nil, ErrorAt(key, "a step must have exactly one action, but has '%v' and '%v'", action.Value, key.Value)
+		default:
+			action = key
+		}
+	}
+
+	if action == nil && branch != nil {
+		return nil, nil, ErrorAt(branch, "'%v' is only allowed together with 'if'", branch.Value)
+	}
+
+	if action == nil {
+		return nil, nil, ErrorAt(n, "a step must have exactly one action, but has none")
+	}
+
+	if branch != nil && action.Value != "if" {
+		return nil, nil, ErrorAt(branch, "'%v' is only allowed together with 'if', not with '%v'", branch.Value, action.Value)
+	}
+
+	return action, mods, nil
+}
+
+// applyModifiers wraps the step according to the given modifier keys of n.
+// A step with 'unless' is skipped if the condition holds, regardless of
+// where the other modifiers are.
+func applyModifiers(step Step, n *yaml.Node, mods []*yaml.Node) (Step, error) {
+	var unless Condition
+
+	for _, mod := range mods {
+		value := mappingValue(n, mod.Value)
+
+		switch mod.Value {
+		case "timeout":
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return nil, ErrorAt(value, "unable to parse '%v' as timeout", describe(value))
+			}
+
+			duration, err := time.ParseDuration(tt)
+
+			if err != nil {
+				return nil, ErrorAt(value, "unable to parse '%v' as timeout: %v", tt, err)
+			}
+
+			step = &Timeout{Step: step, Duration: duration}
+
+			if err = step.Validate(); err != nil {
+				return nil, ErrorAt(value, "%v", err)
+			}
+		case "unless":
+			condition, err := parseCondition(value)
+
+			if err != nil {
+				return nil, err
+			}
+
+			unless = condition
+		}
+	}
+
+	if unless != nil {
+		step = &If{Condition: unless, Else: []Step{step}}
+	}
+
+	return step, nil
+}
+
+func (s *scope) expandSnippet(n *yaml.Node, loc location, using []string) []Step {
+	name, ok := stringValue(n)
+
+	if !ok {
+		s.fail(loc, n, fmt.Errorf("unable to parse '%v' as value of a Use step", describe(n)))
+		return nil
+	}
+
+	if slices.Contains(using, name) {
+		s.fail(loc, n, fmt.Errorf("snippet '%v' uses itself: %v", name, strings.Join(append(using, name), " -> ")))
+		return nil
+	}
+
+	def, found := s.snippets[name]
+
+	if !found {
+		s.fail(loc, n, fmt.Errorf("'%v' is not a known snippet", name))
+		return nil
+	}
+
+	if isNull(def.node) {
+		return nil
+	}
+
+	// problems within the snippet are reported where the snippet is defined
+	snippetLoc := def.loc
+	snippetLoc.tab = loc.tab
+	snippetLoc.step = loc.step
+
+	return s.parseSteps(def.node, snippetLoc, append(slices.Clone(using), name))
+}
+
+// parseIf decodes a conditional step with the keys 'if', 'then' and 'else'.
+func (s *scope) parseIf(n *yaml.Node, loc location, using []string) Step {
+	var ifStep If
+	failures := len(s.errs)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "if":
+			condition, err := parseCondition(value)
+
+			if err != nil {
+				s.fail(loc, value, err)
+				continue
+			}
+
+			ifStep.Condition = condition
+		case "then", "else":
+			if isNull(value) {
+				continue
+			}
+
+			if value.Kind != yaml.SequenceNode {
+				s.fail(loc, value, fmt.Errorf("unable to parse '%v' as '%v' steps of an If step", describe(value), key.Value))
+				continue
+			}
+
+			steps := s.parseSteps(value, loc, using)
+
+			if key.Value == "then" {
+				ifStep.Then = steps
+			} else {
+				ifStep.Else = steps
+			}
+		default:
+			// modifiers are applied by the caller
+		}
+	}
+
+	if len(s.errs) > failures {
+		return nil
+	}
+
+	if err := ifStep.Validate(); err != nil {
+		s.fail(loc, n, err)
+		return nil
+	}
+
+	return &ifStep
+}
+
+// parseCondition accepts a map with exactly one of the keys 'exists', 'url' or 'text'.
+func parseCondition(n *yaml.Node) (Condition, error) {
+	if n.Kind != yaml.MappingNode || len(n.Content) != 2 {
+		return nil, ErrorAt(n, "unable to parse '%v' as condition; expecting one of 'exists', 'url' or 'text'", describe(n))
+	}
+
+	key, value := n.Content[0], resolve(n.Content[1])
+
+	switch key.Value {
+	case "exists":
+		selector, err := DecodeSelector(value, "condition")
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ElementExists{selector}, nil
+	case "url":
+		pattern, ok := stringValue(value)
+
+		if !ok {
+			return nil, ErrorAt(value, "unable to convert '%v' as 'url' value of a condition", describe(value))
+		}
+
+		re, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return nil, ErrorAt(value, "unable to compile '%v' as 'url' value of a condition: %v", pattern, err)
+		}
+
+		return URLMatches{re}, nil
+	case "text":
+		text, ok := stringValue(value)
+
+		if !ok {
+			return nil, ErrorAt(value, "unable to convert '%v' as 'text' value of a condition", describe(value))
+		}
+
+		return TextPresent(text), nil
+	default:
+		return nil, ErrorAt(key, "'%v' is not a known condition", key.Value)
+	}
+}
+
+// parseStep decodes the action of a step with the given value.
+func parseStep(action *yaml.Node, value *yaml.Node) (Step, error) {
+	decoder, found := lookupStep(action.Value)
+
+	if !found {
+		return nil, ErrorAt(action, "'%v' is not a known step", action.Value)
+	}
+
+	step, err := decoder(value)
+
+	if err != nil {
+		var e *Error
+
+		// errors of decoders that do not know better point at the value
+		if !errors.As(err, &e) {
+			err = ErrorAt(value, "%v", err)
+		}
+
+		return nil, err
+	}
+
+	if step == nil {
+		return nil, ErrorAt(action, "decoder of step '%v' returned no step", action.Value)
+	}
+
+	if err := step.Validate(); err != nil {
+		return nil, err
+	}
+
+	return step, nil
+}
+
+func decodeGo(n *yaml.Node) (Step, error) {
+	url, err := DecodeString(n, "Go")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Go(url), nil
+}
+
+func decodeWait(n *yaml.Node) (Step, error) {
+	selector, err := DecodeSelector(n, "Wait")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Wait{selector}, nil
+}
+
+func decodeClick(n *yaml.Node) (Step, error) {
+	selector, err := DecodeSelector(n, "Click")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Click{selector}, nil
+}
+
+func decodeType(n *yaml.Node) (Step, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as value of a Type step", describe(n))
+	}
+
+	var typeStep Type
+	var selectorKey string
+	var query string
+	var by *yaml.Node
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "xpath", "selector":
+			if selectorKey != "" {
+				return nil, ErrorAt(key, "only one of '%v' and '%v' may be given for a Type step", selectorKey, key.Value)
+			}
+
+			selectorKey = key.Value
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return nil, ErrorAt(value, "unable to convert '%v' as '%v' value of a Type step", describe(value), key.Value)
+			}
+
+			query = tt
+		case "by":
+			by = value
+		case "value":
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return nil, ErrorAt(value, "unable to convert '%v' as 'value' value of a Type step", describe(value))
+			}
+
+			typeStep.Value = tt
+		case "secret":
+			secret, err := parseSecret(value, "secret of a Type step")
+
+			if err != nil {
+				return nil, err
+			}
+
+			typeStep.Secret = secret
+		default:
+			return nil, ErrorAt(key, "'%v' is not a known key for a Type step", key.Value)
+		}
+	}
+
+	// named after the key that was given, as the deprecated 'xpath' is still
+	// accepted; without a position, it points at the step like Validate does
+	if selectorKey != "" && query == "" {
+		return nil, &Error{Err: fmt.Errorf("value for %v must not be empty", selectorKey)}
+	}
+
+	// with an explicit strategy, a prefix is part of the query
+	if by != nil {
+		tt, ok := stringValue(by)
+
+		if !ok {
+			return nil, ErrorAt(by, "unable to convert '%v' as 'by' value of a Type step", describe(by))
+		}
+
+		typeStep.Selector = Selector{Query: query, By: Strategy(tt)}
+	} else {
+		typeStep.Selector = ParseSelector(query)
+	}
+
+	if typeStep.Value != "" && !typeStep.Secret.IsZero() {
+		return nil, ErrorAt(n, "only one of 'value' and 'secret' may be given for a Type step")
+	}
+
+	return &typeStep, nil
+}
+
+func decodeViewport(n *yaml.Node) (Viewport, error) {
+	if n.Kind != yaml.MappingNode {
+		return Viewport{}, ErrorAt(n, "unable to parse '%v' as viewport", describe(n))
+	}
+
+	var viewport Viewport
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+		number, ok := floatValue(value)
+
+		if !ok {
+			return Viewport{}, ErrorAt(value, "unable to parse '%v' as '%v' of a viewport", describe(value), key.Value)
+		}
+
+		switch key.Value {
+		case "width", "height":
+			if number != math.Trunc(number) {
+				return Viewport{}, ErrorAt(value, "'%v' of a viewport must be a whole number", key.Value)
+			}
+
+			if key.Value == "width" {
+				viewport.Width = int64(number)
+			} else {
+				viewport.Height = int64(number)
+			}
+		case "deviceScaleFactor":
+			viewport.DeviceScaleFactor = number
+		default:
+			return Viewport{}, ErrorAt(key, "'%v' is not a known key for a viewport", key.Value)
+		}
+	}
+
+	if err := viewport.Validate(); err != nil {
+		return Viewport{}, ErrorAt(n, "%v", err)
+	}
+
+	return viewport, nil
+}
+
+// decodeSecrets reads a map of names to secrets, e.g. the headers of a tab.
+func decodeSecrets(n *yaml.Node, what string) (map[string]Secret, error) {
+	if isNull(n) {
+		return nil, nil
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as map of %vs", describe(n), what)
+	}
+
+	secrets := make(map[string]Secret)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		secret, err := parseSecret(value, fmt.Sprintf("%v '%v'", what, key.Value))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := secret.Validate(); err != nil {
+			return nil, ErrorAt(value, "%v '%v': %v", what, key.Value, err)
+		}
+
+		secrets[key.Value] = secret
+	}
+
+	return secrets, nil
+}
+
+func decodeCookies(n *yaml.Node) ([]Cookie, error) {
+	if isNull(n) {
+		return nil, nil
+	}
+
+	if n.Kind != yaml.SequenceNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as list of cookies", describe(n))
+	}
+
+	var cookies []Cookie
+
+	for _, cookieNode := range n.Content {
+		cookie, err := decodeCookie(resolve(cookieNode))
+
+		if err != nil {
+			return nil, err
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	return cookies, nil
+}
+
+func decodeCookie(n *yaml.Node) (Cookie, error) {
+	if n.Kind != yaml.MappingNode {
+		return Cookie{}, ErrorAt(n, "unable to parse '%v' as cookie", describe(n))
+	}
+
+	var cookie Cookie
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "name", "domain", "path":
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return Cookie{}, ErrorAt(value, "unable to convert '%v' as '%v' value of a cookie", describe(value), key.Value)
+			}
+
+			switch key.Value {
+			case "name":
+				cookie.Name = tt
+			case "domain":
+				cookie.Domain = tt
+			case "path":
+				cookie.Path = tt
+			}
+		case "value":
+			secret, err := parseSecret(value, "value of a cookie")
+
+			if err != nil {
+				return Cookie{}, err
+			}
+
+			cookie.Value = secret
+		case "secure", "httpOnly":
+			var flag bool
+
+			if value.ShortTag() != "!!bool" || value.Decode(&flag) != nil {
+				return Cookie{}, ErrorAt(value, "unable to convert '%v' as '%v' value of a cookie", describe(value), key.Value)
+			}
+
+			if key.Value == "secure" {
+				cookie.Secure = flag
+			} else {
+				cookie.HTTPOnly = flag
+			}
+		default:
+			return Cookie{}, ErrorAt(key, "'%v' is not a known key for a cookie", key.Value)
+		}
+	}
+
+	if err := cookie.Validate(); err != nil {
+		return Cookie{}, ErrorAt(n, "%v", err)
+	}
+
+	return cookie, nil
+}
+
+func decodeAuth(n *yaml.Node) (*Auth, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as auth", describe(n))
+	}
+
+	var auth Auth
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "username", "origin":
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return nil, ErrorAt(value, "unable to convert '%v' as '%v' value of auth", describe(value), key.Value)
+			}
+
+			if key.Value == "username" {
+				auth.Username = tt
+			} else {
+				auth.Origin = tt
+			}
+		case "password":
+			secret, err := parseSecret(value, "password of auth")
+
+			if err != nil {
+				return nil, err
+			}
+
+			auth.Password = secret
+		case "certificate":
+			certificate, err := decodeCertificateFilter(value)
+
+			if err != nil {
+				return nil, err
+			}
+
+			auth.Certificate = certificate
+		default:
+			return nil, ErrorAt(key, "'%v' is not a known key for auth", key.Value)
+		}
+	}
+
+	if err := auth.Validate(); err != nil {
+		return nil, ErrorAt(n, "%v", err)
+	}
+
+	return &auth, nil
+}
+
+func decodeCertificateFilter(n *yaml.Node) (CertificateFilter, error) {
+	if n.Kind != yaml.MappingNode {
+		return CertificateFilter{}, ErrorAt(n, "unable to parse '%v' as certificate", describe(n))
+	}
+
+	var filter CertificateFilter
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+		tt, ok := stringValue(value)
+
+		if !ok {
+			return CertificateFilter{}, ErrorAt(value, "unable to convert '%v' as '%v' value of a certificate", describe(value), key.Value)
+		}
+
+		switch key.Value {
+		case "issuer":
+			filter.Issuer = tt
+		case "subject":
+			filter.Subject = tt
+		default:
+			return CertificateFilter{}, ErrorAt(key, "'%v' is not a known key for a certificate", key.Value)
+		}
+	}
+
+	if filter.IsZero() {
+		return CertificateFilter{}, ErrorAt(n, "a certificate needs an 'issuer' or a 'subject'")
+	}
+
+	return filter, nil
+}
+
+func decodeBlockRules(n *yaml.Node) ([]*BlockRule, error) {
+	if isNull(n) {
+		return nil, nil
+	}
+
+	if n.Kind != yaml.SequenceNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as list of URL patterns to block", describe(n))
+	}
+
+	var rules []*BlockRule
+
+	for _, patternNode := range n.Content {
+		pattern, ok := stringValue(patternNode)
+
+		if !ok {
+			return nil, ErrorAt(patternNode, "unable to parse '%v' as URL pattern to block", describe(patternNode))
+		}
+
+		rule := NewBlockRule(pattern)
+
+		if err := rule.Validate(); err != nil {
+			return nil, ErrorAt(patternNode, "%v", err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func decodeRewriteRules(n *yaml.Node) ([]*RewriteRule, error) {
+	if isNull(n) {
+		return nil, nil
+	}
+
+	if n.Kind != yaml.SequenceNode {
+		return nil, ErrorAt(n, "unable to parse '%v' as list of rewrite rules", describe(n))
+	}
+
+	var rules []*RewriteRule
+
+	for _, ruleNode := range n.Content {
+		ruleNode = resolve(ruleNode)
+
+		if ruleNode.Kind != yaml.MappingNode {
+			return nil, ErrorAt(ruleNode, "unable to parse '%v' as rewrite rule", describe(ruleNode))
+		}
+
+		rule := &RewriteRule{}
+
+		for i := 0; i < len(ruleNode.Content); i += 2 {
+			key, value := ruleNode.Content[i], resolve(ruleNode.Content[i+1])
+
+			if key.Value != "from" && key.Value != "to" {
+				return nil, ErrorAt(key, "'%v' is not a known key for a rewrite rule", key.Value)
+			}
+
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return nil, ErrorAt(value, "unable to convert '%v' as '%v' value of a rewrite rule", describe(value), key.Value)
+			}
+
+			if key.Value == "from" {
+				rule.From = tt
+			} else {
+				rule.To = tt
+			}
+		}
+
+		if err := rule.Validate(); err != nil {
+			return nil, ErrorAt(ruleNode, "%v", err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// DecodeString reads the value of a step that is a plain string.
+func DecodeString(n *yaml.Node, stepName string) (string, error) {
+	s, ok := stringValue(n)
+
+	if !ok {
+		return "", ErrorAt(n, "unable to parse '%v' as value of a %v step", describe(n), stepName)
+	}
+
+	return s, nil
+}
+
+// DecodeSelector accepts either a plain string, optionally prefixed with the
+// strategy (e.g. "css:#login"), or a map with the keys 'selector' and 'by'.
+func DecodeSelector(n *yaml.Node, stepName string) (Selector, error) {
+	if s, ok := stringValue(n); ok {
+		return ParseSelector(s), nil
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return Selector{}, ErrorAt(n, "unable to parse '%v' as value of a %v step", describe(n), stepName)
+	}
+
+	var query string
+	var by *yaml.Node
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+
+		switch key.Value {
+		case "selector":
+			tt, ok := stringValue(value)
+
+			if !ok {
+				return Selector{}, ErrorAt(value, "unable to convert '%v' as 'selector' value of a %v step", describe(value), stepName)
+			}
+
+			query = tt
+		case "by":
+			by = value
+		default:
+			return Selector{}, ErrorAt(key, "'%v' is not a known key for a %v step", key.Value, stepName)
+		}
+	}
+
+	if by == nil {
+		return ParseSelector(query), nil
+	}
+
+	// with an explicit strategy, a prefix is part of the query
+	tt, ok := stringValue(by)
+
+	if !ok {
+		return Selector{}, ErrorAt(by, "unable to convert '%v' as 'by' value of a %v step", describe(by), stepName)
+	}
+
+	selector := Selector{Query: query, By: Strategy(tt)}
+
+	if err := selector.By.Validate(); err != nil {
+		return Selector{}, ErrorAt(by, "%v", err)
+	}
+
+	return selector, nil
+}
+
+// parseSecret accepts either a string like "env:NAME", "file:/run/secrets/x"
+// or a literal, or a map with one of the keys 'value', 'env' or 'file', or
+// with the keys 'encrypted' and 'keyFile'. Numbers and booleans are literals,
+// e.g. a PIN. Errors name what the secret is for, given as what, but never
+// contain its value.
+func parseSecret(n *yaml.Node, what string) (Secret, error) {
+	n = resolve(n)
+
+	if s, ok := secretValue(n); ok {
+		// secrets are not interpolated, see secretKeys
+		if strings.Contains(s, "${") {
+			return Secret{}, ErrorAt(n, "%v is taken as written; reference an environment variable as 'env:NAME', or give a literal containing '${' as 'value'", what)
+		}
+
+		return ParseSecret(s), nil
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return Secret{}, ErrorAt(n, "%v must be a string, a number or a map", what)
+	}
+
+	attributes := make(map[string]string)
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+		tt, ok := secretValue(value)
+
+		if !ok {
+			return Secret{}, ErrorAt(value, "'%v' of %v must be a string or a number", key.Value, what)
+		}
+
+		attributes[key.Value] = tt
+	}
+
+	if len(attributes) == 1 {
+		if value, found := attributes["value"]; found {
+			return Secret{ref: value}, nil
+		}
+
+		if name, found := attributes["env"]; found {
+			return Secret{source: secretEnv, ref: name}, nil
+		}
+
+		if path, found := attributes["file"]; found {
+			return Secret{source: secretFile, ref: path}, nil
+		}
+	}
+
+	path, hasPath := attributes["encrypted"]
+	keyFile, hasKeyFile := attributes["keyFile"]
+
+	if len(attributes) == 2 && hasPath && hasKeyFile {
+		return EncryptedSecret(path, keyFile), nil
+	}
+
+	return Secret{}, ErrorAt(n, "%v needs either 'value', 'env', 'file', or 'encrypted' with 'keyFile'", what)
+}
+
+// secretValue returns the value of n as written if it is a string, number or
+// boolean scalar, so that e.g. the PIN 0042 keeps its leading zeros.
+func secretValue(n *yaml.Node) (string, bool) {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return "", false
+	}
+
+	switch n.ShortTag() {
+	case "!!str", "!!int", "!!float", "!!bool":
+		return n.Value, true
+	default:
+		return "", false
+	}
+}
+
+// resolve follows aliases to the node they refer to.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	return n
+}
+
+func isNull(n *yaml.Node) bool {
+	return n == nil || n.Kind == 0 || (n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null")
+}
+
+// stringValue returns the value of n if it is a string scalar.
+func stringValue(n *yaml.Node) (string, bool) {
+	n = resolve(n)
+
+	if n == nil || n.Kind != yaml.ScalarNode || n.ShortTag() != "!!str" {
+		return "", false
+	}
+
+	return n.Value, true
+}
+
+// mappingValue returns the value for key if n is a mapping that has it.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return resolve(n.Content[i+1])
+		}
+	}
+
+	return nil
+}
+
+// floatValue accepts integers and floats, also as strings (e.g. after interpolation).
+func floatValue(n *yaml.Node) (float64, bool) {
+	n = resolve(n)
+
+	if n.Kind != yaml.ScalarNode {
+		return 0, false
+	}
+
+	switch n.Tag {
+	case "!!int", "!!float", "!!str":
+		f, err := strconv.ParseFloat(n.Value, 64)
+		return f, err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)
+	default:
+		return 0, false
+	}
+}
+
+// describe presents the value of n for error messages.
+func describe(n *yaml.Node) string {
+	var value interface{}
+
+	if err := n.Decode(&value); err != nil {
+		return n.Value
+	}
+
+	return fmt.Sprint(value)
 }
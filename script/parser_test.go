@@ -32,7 +32,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to parse '<nil>' as value of a Go step"))
+					Expect(err).To(MatchError("5:10: tab 'Missing Value', step 2: unable to parse '<nil>' as value of a Go step"))
 				})
 
 				It("has no tabs", func() {
@@ -55,7 +55,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("value must not be empty"))
+					Expect(err).To(MatchError("5:7: tab 'Empty Value', step 2: value must not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -80,7 +80,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to parse '<nil>' as value of a Wait step"))
+					Expect(err).To(MatchError("4:12: tab 'Missing Value', step 1: unable to parse '<nil>' as value of a Wait step"))
 				})
 
 				It("has no tabs", func() {
@@ -103,7 +103,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("value must not be empty"))
+					Expect(err).To(MatchError("4:7: tab 'Empty Value', step 1: value must not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -128,7 +128,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to parse '<nil>' as value of a Type step"))
+					Expect(err).To(MatchError("4:12: tab 'Missing Value', step 1: unable to parse '<nil>' as value of a Type step"))
 				})
 
 				It("has no tabs", func() {
@@ -151,7 +151,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to parse '' as value of a Type step"))
+					Expect(err).To(MatchError("4:13: tab 'Empty Value', step 1: unable to parse '' as value of a Type step"))
 				})
 
 				It("has no tabs", func() {
@@ -176,7 +176,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to convert '<nil>' as 'xpath' value of a Type step"))
+					Expect(err).To(MatchError("5:15: tab 'Empty Value', step 1: unable to convert '<nil>' as 'xpath' value of a Type step"))
 				})
 
 				It("has no tabs", func() {
@@ -201,7 +201,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("value for xpath must not be empty"))
+					Expect(err).To(MatchError("4:7: tab 'Empty Value', step 1: value for xpath must not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -226,7 +226,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to convert '<nil>' as 'value' value of a Type step"))
+					Expect(err).To(MatchError("6:15: tab 'Empty Value', step 1: unable to convert '<nil>' as 'value' value of a Type step"))
 				})
 
 				It("has no tabs", func() {
@@ -251,7 +251,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("either value or secret must be provided and not be empty"))
+					Expect(err).To(MatchError("4:7: tab 'Empty Value', step 1: either value or secret must be provided and not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -276,7 +276,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to convert '<nil>' as 'secret' value of a Type step"))
+					Expect(err).To(MatchError("6:16: tab 'Empty Value', step 1: secret of a Type step must be a string, a number or a map"))
 				})
 
 				It("has no tabs", func() {
@@ -301,7 +301,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("either value or secret must be provided and not be empty"))
+					Expect(err).To(MatchError("4:7: tab 'Empty Value', step 1: either value or secret must be provided and not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -326,7 +326,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("unable to parse '<nil>' as value of a Click step"))
+					Expect(err).To(MatchError("4:13: tab 'Missing Value', step 1: unable to parse '<nil>' as value of a Click step"))
 				})
 
 				It("has no tabs", func() {
@@ -349,7 +349,7 @@ var _ = Describe("Parser", func() {
 				})
 
 				It("has the expected error", func() {
-					Expect(err).To(MatchError("value must not be empty"))
+					Expect(err).To(MatchError("4:7: tab 'Empty Value', step 1: value must not be empty"))
 				})
 
 				It("has no tabs", func() {
@@ -359,6 +359,240 @@ var _ = Describe("Parser", func() {
 		})
 	})
 
+	Context("several problems", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: First
+  script:
+    - go: https://example.com
+    - click: ""
+- name: Second
+  script:
+    - wait: foo
+    - jump: bar
+    - type:
+        xpath: baz
+- script:
+    - go: 42
+`)
+		})
+
+		JustBeforeEach(func() {
+			tabs, err = script.NewParser().WithPath("kiosk.yml").Parse(scrpt)
+		})
+
+		It("reports all of them", func() {
+			Expect(err).To(MatchError(`kiosk.yml:5:7: tab 'First', step 2: value must not be empty
+kiosk.yml:9:7: tab 'Second', step 2: 'jump' is not a known step
+kiosk.yml:10:7: tab 'Second', step 3: either value or secret must be provided and not be empty
+kiosk.yml:13:11: tab '#3', step 1: unable to parse '42' as value of a Go step`))
+		})
+
+		It("has the positions", func() {
+			var problems script.Errors
+			Expect(err).To(BeAssignableToTypeOf(problems))
+			problems = err.(script.Errors)
+			Expect(problems).To(HaveLen(4))
+
+			Expect(problems[1].File).To(Equal("kiosk.yml"))
+			Expect(problems[1].Line).To(Equal(9))
+			Expect(problems[1].Column).To(Equal(7))
+			Expect(problems[1].Tab).To(Equal("Second"))
+			Expect(problems[1].Step).To(Equal(2))
+		})
+
+		It("has no tabs", func() {
+			Expect(tabs).To(BeEmpty())
+		})
+	})
+
+	Context("malformed YAML", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Broken
+  script: [
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("has no tabs", func() {
+			Expect(tabs).To(BeEmpty())
+		})
+	})
+
+	Context("unknown key of a tab", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Typo
+  scirpt:
+    - go: https://example.com
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("3:3: tab 'Typo': 'scirpt' is not a known key for a tab"))
+		})
+	})
+
+	Context("selector strategies", func() {
+		DescribeTable("click with a prefixed selector",
+			func(selector string, expectedQuery string, expectedStrategy script.Strategy) {
+				tabs, err := script.Parse([]byte(`
+- name: Strategies
+  script:
+    - click: "` + selector + `"
+`))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tabs[0].Steps).To(HaveLen(1))
+
+				click, ok := tabs[0].Steps[0].(script.Click)
+				Expect(ok).To(BeTrue())
+				Expect(click.Query).To(Equal(expectedQuery))
+				Expect(click.By).To(Equal(expectedStrategy))
+			},
+			Entry("default", "//p/a", "//p/a", script.ByDefault),
+			Entry("css", "css:#login", "#login", script.ByCSS),
+			Entry("xpath", "xpath://p/a", "//p/a", script.ByXPath),
+			Entry("id", "id:login", "login", script.ByID),
+			Entry("text", "text:Sign in", "Sign in", script.ByText),
+			Entry("search", "search:Sign in", "Sign in", script.BySearch),
+			Entry("unknown prefix", "foo:bar", "foo:bar", script.ByDefault),
+		)
+
+		DescribeTable("wait with an explicit strategy",
+			func(strategy string, expectedStrategy script.Strategy) {
+				tabs, err := script.Parse([]byte(`
+- name: Strategies
+  script:
+    - wait:
+        selector: login
+        by: ` + strategy + `
+`))
+				Expect(err).ToNot(HaveOccurred())
+
+				wait, ok := tabs[0].Steps[0].(script.Wait)
+				Expect(ok).To(BeTrue())
+				Expect(wait.Query).To(Equal("login"))
+				Expect(wait.By).To(Equal(expectedStrategy))
+			},
+			Entry("css", "css", script.ByCSS),
+			Entry("xpath", "xpath", script.ByXPath),
+			Entry("id", "id", script.ByID),
+			Entry("text", "text", script.ByText),
+			Entry("search", "search", script.BySearch),
+		)
+
+		Context("type with an explicit strategy", func() {
+			BeforeEach(func() {
+				scrpt = []byte(`
+- name: Strategies
+  script:
+    - type:
+        selector: "#user"
+        by: css
+        value: jdoe
+`)
+			})
+
+			It("parses", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("has the strategy", func() {
+				typeStep, ok := tabs[0].Steps[0].(*script.Type)
+				Expect(ok).To(BeTrue())
+				Expect(typeStep.By).To(Equal(script.ByCSS))
+			})
+
+			It("presents itself as expected", func() {
+				Expect(tabs[0].Steps[0].String()).To(Equal("type 'jdoe' into the element addressed by css '#user'"))
+			})
+		})
+
+		Context("unknown strategy", func() {
+			BeforeEach(func() {
+				scrpt = []byte(`
+- name: Strategies
+  script:
+    - click:
+        selector: login
+        by: magic
+`)
+			})
+
+			It("does not parse", func() {
+				Expect(err).To(MatchError("6:13: tab 'Strategies', step 1: 'magic' is not a known selector strategy"))
+			})
+
+			It("has no tabs", func() {
+				Expect(tabs).To(BeEmpty())
+			})
+		})
+
+		Context("unknown key", func() {
+			BeforeEach(func() {
+				scrpt = []byte(`
+- name: Strategies
+  script:
+    - wait:
+        selector: login
+        using: css
+`)
+			})
+
+			It("does not parse", func() {
+				Expect(err).To(MatchError("6:9: tab 'Strategies', step 1: 'using' is not a known key for a Wait step"))
+			})
+		})
+	})
+
+	Context("error policy", func() {
+		Context("known", func() {
+			BeforeEach(func() {
+				scrpt = []byte(`
+- name: Intranet
+  onError: placeholder
+  script:
+    - go: https://intranet.example.com
+`)
+			})
+
+			It("parses", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("has the policy", func() {
+				Expect(tabs[0].OnError).To(Equal(script.OnErrorPlaceholder))
+			})
+
+			It("knows the URL", func() {
+				Expect(tabs[0].URL()).To(Equal("https://intranet.example.com"))
+			})
+		})
+
+		Context("unknown", func() {
+			BeforeEach(func() {
+				scrpt = []byte(`
+- name: Intranet
+  onError: ignore
+  script:
+    - go: https://intranet.example.com
+`)
+			})
+
+			It("does not parse", func() {
+				Expect(err).To(MatchError("3:12: tab 'Intranet': 'ignore' is not a known error policy"))
+			})
+
+			It("has no tabs", func() {
+				Expect(tabs).To(BeEmpty())
+			})
+		})
+	})
+
 	Context("valid script", func() {
 		BeforeEach(func() {
 			scrpt = []byte(`
@@ -0,0 +1,70 @@
+package script
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StepDecoder creates a step from the value of its action, e.g. the URL of
+// 'go: https://example.com'. Problems are best reported with ErrorAt, so that
+// they point at the offending part of the value. The parser validates the
+// step afterwards.
+type StepDecoder func(value *yaml.Node) (Step, error)
+
+var (
+	stepDecodersMutex sync.RWMutex
+	stepDecoders      = make(map[string]StepDecoder)
+)
+
+// keys of a step that the parser handles itself
+var reservedKeys = []string{"use", "if", "then", "else"}
+
+func init() {
+	RegisterStep("go", decodeGo)
+	RegisterStep("wait", decodeWait)
+	RegisterStep("click", decodeClick)
+	RegisterStep("type", decodeType)
+}
+
+// RegisterStep makes a step with the given action available to scripts.
+// Steps that implement yaml.Marshaler can be written by Marshal, too.
+// RegisterStep panics if the action is already taken.
+func RegisterStep(action string, decoder StepDecoder) {
+	stepDecodersMutex.Lock()
+	defer stepDecodersMutex.Unlock()
+
+	if decoder == nil {
+		panic(fmt.Sprintf("script: decoder of step '%v' is nil", action))
+	}
+
+	if slices.Contains(reservedKeys, action) || slices.Contains(modifiers, action) {
+		panic(fmt.Sprintf("script: '%v' is reserved and cannot be registered as step", action))
+	}
+
+	if _, found := stepDecoders[action]; found {
+		panic(fmt.Sprintf("script: step '%v' is registered twice", action))
+	}
+
+	stepDecoders[action] = decoder
+}
+
+// Steps lists the actions of all registered steps.
+func Steps() []string {
+	stepDecodersMutex.RLock()
+	defer stepDecodersMutex.RUnlock()
+
+	return slices.Sorted(maps.Keys(stepDecoders))
+}
+
+func lookupStep(action string) (StepDecoder, bool) {
+	stepDecodersMutex.RLock()
+	defer stepDecodersMutex.RUnlock()
+
+	decoder, found := stepDecoders[action]
+
+	return decoder, found
+}
@@ -0,0 +1,118 @@
+package script_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+	"uhlig.it/kiosk/script"
+)
+
+// Scroll is a step like the ones registered by other packages.
+type Scroll int
+
+func (s Scroll) Action() chromedp.Action {
+	return chromedp.Evaluate(fmt.Sprintf("window.scrollBy(0, %d)", int(s)), nil)
+}
+
+func (s Scroll) String() string {
+	return fmt.Sprintf("scroll by %d pixels", int(s))
+}
+
+func (s Scroll) Validate() error {
+	if s == 0 {
+		return errors.New("scrolling by 0 pixels does nothing")
+	}
+
+	return nil
+}
+
+func init() {
+	script.RegisterStep("scroll", func(value *yaml.Node) (script.Step, error) {
+		var pixels int
+
+		if err := value.Decode(&pixels); err != nil {
+			return nil, script.ErrorAt(value, "unable to parse '%v' as value of a Scroll step", value.Value)
+		}
+
+		return Scroll(pixels), nil
+	})
+
+	script.RegisterStep("sleep", func(value *yaml.Node) (script.Step, error) {
+		return nil, errors.New("sleeping is not supported")
+	})
+
+	script.RegisterStep("nothing", func(value *yaml.Node) (script.Step, error) {
+		return nil, nil
+	})
+}
+
+var _ = Describe("Registry", func() {
+	parse := func(step string) ([]*script.Tab, error) {
+		return script.Parse([]byte(`
+- name: Registry
+  script:
+    - ` + step + `
+`))
+	}
+
+	It("lists the steps", func() {
+		Expect(script.Steps()).To(Equal([]string{"click", "go", "nothing", "scroll", "sleep", "type", "wait"}))
+	})
+
+	It("parses a registered step", func() {
+		tabs, err := parse(`scroll: 200`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Steps).To(Equal([]script.Step{Scroll(200)}))
+	})
+
+	It("applies modifiers to a registered step", func() {
+		tabs, err := parse(`{ scroll: 200, timeout: 1s }`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Steps[0].String()).To(Equal("scroll by 200 pixels within 1s"))
+	})
+
+	DescribeTable("reports problems",
+		func(step string, expected string) {
+			tabs, err := parse(step)
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("decoder error with position",
+			`scroll: far`,
+			"4:15: tab 'Registry', step 1: unable to parse 'far' as value of a Scroll step"),
+		Entry("decoder error without position",
+			`sleep: 5s`,
+			"4:14: tab 'Registry', step 1: sleeping is not supported"),
+		Entry("invalid step",
+			`scroll: 0`,
+			"4:7: tab 'Registry', step 1: scrolling by 0 pixels does nothing"),
+		Entry("decoder without a step",
+			`nothing: at all`,
+			"4:7: tab 'Registry', step 1: decoder of step 'nothing' returned no step"),
+	)
+
+	DescribeTable("refuses to register",
+		func(action string) {
+			Expect(func() {
+				script.RegisterStep(action, func(value *yaml.Node) (script.Step, error) { return nil, nil })
+			}).To(Panic())
+		},
+		Entry("a built-in step", "go"),
+		Entry("a registered step", "scroll"),
+		Entry("snippets", "use"),
+		Entry("conditions", "if"),
+		Entry("modifiers", "timeout"),
+	)
+
+	It("is part of the schema", func() {
+		raw, err := script.Schema()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(raw, new(map[string]any))).To(Succeed())
+		Expect(string(raw)).To(ContainSubstring(`"scroll"`))
+	})
+})
@@ -0,0 +1,199 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HTTPClient fetches remote scripts and the files they include.
+var HTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsURL tells whether the name of a script refers to an HTTP(S) server instead of a file.
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// Remote is a script served via HTTP. It remembers the ETag of the last
+// response, so that an unchanged script is not transferred again. The files
+// the script includes via HTTP are checked for changes as well.
+type Remote struct {
+	url      string
+	client   *http.Client
+	etag     string
+	content  []byte
+	format   Format
+	includes []*Remote
+}
+
+func NewRemote(url string) *Remote {
+	return &Remote{
+		url:    url,
+		client: HTTPClient,
+	}
+}
+
+func (r *Remote) WithClient(client *http.Client) *Remote {
+	r.client = client
+	return r
+}
+
+func (r *Remote) URL() string {
+	return r.url
+}
+
+// Format tells the format of the script as announced by the server, or as detected from its URL or content.
+func (r *Remote) Format() Format {
+	return r.format
+}
+
+// WatchIncludes makes Fetch report a change if one of the given files
+// changed, e.g. the ones the script includes. Local files are not watched.
+func (r *Remote) WatchIncludes(paths []string) {
+	var includes []*Remote
+
+	for _, path := range paths {
+		if !IsURL(path) {
+			continue
+		}
+
+		// the ones watched already keep their ETag
+		if i := slices.IndexFunc(r.includes, func(include *Remote) bool { return include.url == path }); i >= 0 {
+			includes = append(includes, r.includes[i])
+			continue
+		}
+
+		includes = append(includes, NewRemote(path).WithClient(r.client))
+	}
+
+	r.includes = includes
+}
+
+// Fetch returns the current content of the script and whether it or one of
+// the watched includes changed since the previous call. The first successful
+// call always reports a change.
+func (r *Remote) Fetch(ctx context.Context) ([]byte, bool, error) {
+	content, changed, err := r.fetch(ctx)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, include := range r.includes {
+		// a newly watched include was just read along with the script
+		fetchedBefore := include.content != nil
+		_, includeChanged, err := include.fetch(ctx)
+
+		// an include that cannot be fetched is tried again on the next call
+		if err == nil && includeChanged && fetchedBefore {
+			changed = true
+		}
+	}
+
+	return content, changed, nil
+}
+
+func (r *Remote) fetch(ctx context.Context) ([]byte, bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if r.etag != "" {
+		request.Header.Set("If-None-Match", r.etag)
+	}
+
+	response, err := r.client.Do(request)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && r.content != nil {
+		return r.content, false, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("could not fetch %v: %v", r.url, response.Status)
+	}
+
+	content, err := io.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("could not fetch %v: %w", r.url, err)
+	}
+
+	// servers without ETags still transfer the script, but it may not have changed
+	changed := r.content == nil || !bytes.Equal(content, r.content)
+
+	r.etag = response.Header.Get("ETag")
+	r.content = content
+
+	if format, ok := FormatOfMediaType(response.Header.Get("Content-Type")); ok {
+		r.format = format
+	} else {
+		r.format = DetectFormat(r.url, content)
+	}
+
+	return content, changed, nil
+}
+
+// resolveInclude determines the path or URL of a file included by the script named base.
+func resolveInclude(base string, name string) (string, error) {
+	if IsURL(name) {
+		return name, nil
+	}
+
+	if IsURL(base) {
+		baseURL, err := url.Parse(base)
+
+		if err != nil {
+			return "", err
+		}
+
+		reference, err := url.Parse(name)
+
+		if err != nil {
+			return "", err
+		}
+
+		return baseURL.ResolveReference(reference).String(), nil
+	}
+
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+
+	return filepath.Join(filepath.Dir(base), name), nil
+}
+
+func readInclude(path string) ([]byte, error) {
+	if !IsURL(path) {
+		return os.ReadFile(path)
+	}
+
+	response, err := HTTPClient.Get(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v", response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
@@ -0,0 +1,170 @@
+package script_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Remote", func() {
+	var server *httptest.Server
+	var content, common atomic.Value
+	var transfers atomic.Int32
+
+	// serve answers with the ETag of the content, or 304 Not Modified.
+	serve := func(w http.ResponseWriter, r *http.Request, content string) {
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(content)))
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		transfers.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content))
+	}
+
+	BeforeEach(func() {
+		content.Store(`[[tabs]]
+name = "org"
+script = [ { go = "https://example.org" } ]
+`)
+		transfers.Store(0)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/kiosk", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/toml")
+			serve(w, r, content.Load().(string))
+		})
+		mux.HandleFunc("/scripts/main.yml", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`
+include: common.yml
+tabs:
+  - name: com
+    script:
+      - go: https://${host}
+      - use: login
+`))
+		})
+		common.Store(`
+vars:
+  host: example.com
+snippets:
+  login:
+    - click: //button
+`)
+		mux.HandleFunc("/scripts/common.yml", func(w http.ResponseWriter, r *http.Request) {
+			serve(w, r, common.Load().(string))
+		})
+		mux.HandleFunc("/scripts/broken.yml", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`
+include: missing.yml
+tabs: []
+`))
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	It("fetches the script", func() {
+		remote := script.NewRemote(server.URL + "/kiosk").WithClient(server.Client())
+		markup, changed, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(remote.Format()).To(Equal(script.FormatTOML))
+
+		tabs, err := script.NewParser().WithPath(remote.URL()).WithFormat(remote.Format()).Parse(markup)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+		Expect(tabs[0].URL()).To(Equal("https://example.org"))
+	})
+
+	It("does not transfer an unchanged script again", func() {
+		remote := script.NewRemote(server.URL + "/kiosk").WithClient(server.Client())
+		first, _, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		second, changed, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(second).To(Equal(first))
+		Expect(transfers.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("notices changes", func() {
+		remote := script.NewRemote(server.URL + "/kiosk").WithClient(server.Client())
+		_, _, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		content.Store(`[[tabs]]
+name = "com"
+script = [ { go = "https://example.com" } ]
+`)
+		markup, changed, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(string(markup)).To(ContainSubstring("example.com"))
+		Expect(transfers.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("fails for missing scripts", func() {
+		_, _, err := script.NewRemote(server.URL + "/missing").WithClient(server.Client()).Fetch(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("404 Not Found")))
+	})
+
+	It("resolves includes relative to the URL", func() {
+		remote := script.NewRemote(server.URL + "/scripts/main.yml").WithClient(server.Client())
+		markup, _, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		tabs, err := script.NewParser().WithPath(remote.URL()).Parse(markup)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+		Expect(tabs[0].URL()).To(Equal("https://example.com"))
+		Expect(tabs[0].Steps).To(HaveLen(2))
+	})
+
+	It("notices changes of includes", func() {
+		remote := script.NewRemote(server.URL + "/scripts/main.yml").WithClient(server.Client())
+		markup, _, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		includes, err := script.NewParser().WithPath(remote.URL()).Includes(markup)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(includes).To(Equal([]string{server.URL + "/scripts/common.yml"}))
+		remote.WatchIncludes(includes)
+
+		_, changed, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+
+		common.Store(`
+vars:
+  host: example.net
+`)
+		_, changed, err = remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+
+		_, changed, err = remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+	})
+
+	It("reports missing includes", func() {
+		remote := script.NewRemote(server.URL + "/scripts/broken.yml").WithClient(server.Client())
+		markup, _, err := remote.Fetch(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = script.NewParser().WithPath(remote.URL()).Parse(markup)
+		Expect(err).To(MatchError(server.URL + "/scripts/broken.yml:2:10: could not include missing.yml: 404 Not Found"))
+	})
+})
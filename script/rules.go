@@ -0,0 +1,140 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Rule changes what happens to some of the requests of a tab, and counts how
+// often it did.
+type Rule interface {
+	Validate() error
+	String() string
+	Hits() int64
+}
+
+// BlockRule fails requests whose URL matches Pattern, e.g. of ads, analytics
+// or videos. In the pattern, * stands for any number of characters and ?
+// for a single one.
+type BlockRule struct {
+	Pattern string
+	matcher *regexp.Regexp
+	hits    atomic.Int64
+}
+
+func NewBlockRule(pattern string) *BlockRule {
+	var expression strings.Builder
+
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expression.WriteString(".*")
+		case '?':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return &BlockRule{
+		Pattern: pattern,
+		matcher: regexp.MustCompile("^" + expression.String() + "$"),
+	}
+}
+
+func (b *BlockRule) Matches(url string) bool {
+	return b.matcher.MatchString(url)
+}
+
+func (b *BlockRule) Validate() error {
+	if b.Pattern == "" {
+		return errors.New("pattern of a block rule must not be empty")
+	}
+
+	return nil
+}
+
+func (b *BlockRule) String() string {
+	return fmt.Sprintf("block %v", b.Pattern)
+}
+
+func (b *BlockRule) Hits() int64 {
+	return b.hits.Load()
+}
+
+// RewriteRule sends requests for URLs starting with From to the URL with To
+// instead, e.g. to an internal mirror. The page does not notice.
+type RewriteRule struct {
+	From string
+	To   string
+	hits atomic.Int64
+}
+
+func NewRewriteRule(from, to string) *RewriteRule {
+	return &RewriteRule{From: from, To: to}
+}
+
+// Rewrite returns the rewritten URL, and whether the rule applies at all.
+func (r *RewriteRule) Rewrite(url string) (string, bool) {
+	rest, found := strings.CutPrefix(url, r.From)
+
+	if !found {
+		return url, false
+	}
+
+	return r.To + rest, true
+}
+
+func (r *RewriteRule) Validate() error {
+	if r.From == "" || r.To == "" {
+		return errors.New("a rewrite rule needs both 'from' and 'to'")
+	}
+
+	return nil
+}
+
+func (r *RewriteRule) String() string {
+	return fmt.Sprintf("rewrite %v to %v", r.From, r.To)
+}
+
+func (r *RewriteRule) Hits() int64 {
+	return r.hits.Load()
+}
+
+// Rules lists the block rules of the tab first, as they take precedence.
+func (n *Tab) Rules() []Rule {
+	var rules []Rule
+
+	for _, b := range n.Block {
+		rules = append(rules, b)
+	}
+
+	for _, r := range n.Rewrite {
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// route decides what happens to a paused request, counting the rule that
+// applies, if any.
+func (n *Tab) route(url string) (blocked bool, rewritten string) {
+	for _, b := range n.Block {
+		if b.Matches(url) {
+			b.hits.Add(1)
+			return true, ""
+		}
+	}
+
+	for _, r := range n.Rewrite {
+		if rewritten, found := r.Rewrite(url); found {
+			r.hits.Add(1)
+			return false, rewritten
+		}
+	}
+
+	return false, ""
+}
@@ -0,0 +1,107 @@
+package script_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Rules", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Lobby
+  block:
+    - "*://*.doubleclick.net/*"
+    - "*.mp4"
+  rewrite:
+    - from: https://cdn.example.com/
+      to: https://mirror.internal/cdn/
+  script:
+    - go: https://example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Block).To(HaveLen(2))
+		Expect(tabs[0].Block[0].Pattern).To(Equal("*://*.doubleclick.net/*"))
+		Expect(tabs[0].Rewrite).To(Equal([]*script.RewriteRule{script.NewRewriteRule("https://cdn.example.com/", "https://mirror.internal/cdn/")}))
+
+		var descriptions []string
+
+		for _, rule := range tabs[0].Rules() {
+			descriptions = append(descriptions, rule.String())
+			Expect(rule.Hits()).To(BeZero())
+		}
+
+		Expect(descriptions).To(Equal([]string{
+			"block *://*.doubleclick.net/*",
+			"block *.mp4",
+			"rewrite https://cdn.example.com/ to https://mirror.internal/cdn/",
+		}))
+	})
+
+	DescribeTable("block patterns",
+		func(pattern string, url string, expected bool) {
+			Expect(script.NewBlockRule(pattern).Matches(url)).To(Equal(expected))
+		},
+		Entry("any subdomain", "*://*.doubleclick.net/*", "https://ad.doubleclick.net/pixel?x=1", true),
+		Entry("other domain", "*://*.doubleclick.net/*", "https://example.com/doubleclick.net/", false),
+		Entry("extension", "*.mp4", "https://example.com/intro.mp4", true),
+		Entry("extension with query", "*.mp4", "https://example.com/intro.mp4?autoplay=1", false),
+		Entry("single character", "https://example.com/v?.js", "https://example.com/v2.js", true),
+		Entry("regular expression characters are literal", "https://example.com/(a+b)", "https://example.com/(a+b)", true),
+		Entry("whole URL only", "https://example.com/", "https://example.com/page", false),
+	)
+
+	DescribeTable("rewriting",
+		func(url string, expected string, applies bool) {
+			rewritten, found := script.NewRewriteRule("https://cdn.example.com/", "https://mirror.internal/cdn/").Rewrite(url)
+			Expect(found).To(Equal(applies))
+			Expect(rewritten).To(Equal(expected))
+		},
+		Entry("matching prefix", "https://cdn.example.com/lib.js", "https://mirror.internal/cdn/lib.js", true),
+		Entry("other URL", "https://example.com/lib.js", "https://example.com/lib.js", false),
+	)
+
+	DescribeTable("rejected",
+		func(setting string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Rules
+  ` + setting + `
+  script:
+    - go: https://example.com
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("block that is not a list", `block: "*.mp4"`, "3:10: tab 'Rules': unable to parse '*.mp4' as list of URL patterns to block"),
+		Entry("empty pattern", `block: [ "" ]`, "3:12: tab 'Rules': pattern of a block rule must not be empty"),
+		Entry("pattern that is not a string", `block: [ { url: x } ]`, "3:12: tab 'Rules': unable to parse 'map[url:x]' as URL pattern to block"),
+		Entry("rewrite that is not a list", `rewrite: { from: a, to: b }`, "3:12: tab 'Rules': unable to parse 'map[from:a to:b]' as list of rewrite rules"),
+		Entry("rewrite without target", `rewrite: [ { from: "https://a/" } ]`, "3:14: tab 'Rules': a rewrite rule needs both 'from' and 'to'"),
+		Entry("unknown rewrite key", `rewrite: [ { from: a, to: b, regexp: true } ]`, "3:32: tab 'Rules': 'regexp' is not a known key for a rewrite rule"),
+	)
+
+	It("round-trips", func() {
+		tabs := []*script.Tab{{
+			Name:    "Lobby",
+			Block:   []*script.BlockRule{script.NewBlockRule("*.mp4")},
+			Rewrite: []*script.RewriteRule{script.NewRewriteRule("https://cdn.example.com/", "https://mirror.internal/")},
+			Steps:   []script.Step{script.Go("https://example.com")},
+		}}
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal(`- name: Lobby
+  block:
+    - '*.mp4'
+  rewrite:
+    - from: https://cdn.example.com/
+      to: https://mirror.internal/
+  script:
+    - go: https://example.com
+`))
+
+		parsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(tabs))
+	})
+})
@@ -0,0 +1,389 @@
+package script
+
+import (
+	"encoding/json"
+)
+
+// SchemaID identifies the JSON Schema of the script format.
+const SchemaID = "https://uhlig.it/kiosk/script.schema.json"
+
+type object = map[string]any
+
+// stepSchemas describes the value of each built-in action a step may have.
+var stepSchemas = map[string]object{
+	"go": {
+		"description": "navigate to the given URL",
+		"type":        "string",
+		"format":      "uri",
+	},
+	"wait": {
+		"description": "wait until the element addressed by the selector is visible",
+		"$ref":        "#/$defs/selector",
+	},
+	"click": {
+		"description": "click the element addressed by the selector",
+		"$ref":        "#/$defs/selector",
+	},
+	"type": {
+		"description": "type a value or a secret into the element addressed by the selector",
+		"type":        "object",
+		"properties": object{
+			"selector": object{"type": "string"},
+			"xpath":    object{"type": "string", "description": "deprecated; use 'selector'"},
+			"by":       object{"$ref": "#/$defs/strategy"},
+			"value":    object{"type": "string"},
+			"secret":   object{"$ref": "#/$defs/secret"},
+		},
+		"oneOf": []object{
+			{"required": []string{"selector"}},
+			{"required": []string{"xpath"}},
+		},
+		"not":                  object{"required": []string{"value", "secret"}},
+		"additionalProperties": false,
+	},
+}
+
+var useSchema = object{
+	"description": "insert the steps of the snippet with the given name",
+	"type":        "string",
+}
+
+// Schema returns a JSON Schema (draft 2020-12) of the script format, e.g. for editors to validate and autocomplete scripts.
+func Schema() ([]byte, error) {
+	return json.MarshalIndent(schema(), "", "  ")
+}
+
+func schema() object {
+	steps := []object{
+		stepWithAction("if", object{"$ref": "#/$defs/condition"}),
+		stepWithAction("use", useSchema),
+	}
+
+	for _, action := range Steps() {
+		value, found := stepSchemas[action]
+
+		if !found {
+			// the value of a registered step may be anything
+			value = object{}
+		}
+
+		steps = append(steps, stepWithAction(action, value))
+	}
+
+	return object{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     SchemaID,
+		"title":   "kiosk script",
+		"oneOf": []object{
+			{"$ref": "#/$defs/tabs"},
+			{
+				"type": "object",
+				"properties": object{
+					"include": object{
+						"description": "files to include, relative to the including one",
+						"oneOf": []object{
+							{"type": "string"},
+							{"type": "array", "items": object{"type": "string"}},
+						},
+					},
+					"vars": object{
+						"type":                 "object",
+						"additionalProperties": object{"type": []string{"string", "number", "boolean"}},
+					},
+					"snippets": object{
+						"type":                 "object",
+						"additionalProperties": object{"$ref": "#/$defs/steps"},
+					},
+					"tabs": object{"$ref": "#/$defs/tabs"},
+					"screens": object{
+						"type":  "array",
+						"items": object{"$ref": "#/$defs/screen"},
+					},
+				},
+				"additionalProperties": false,
+			},
+		},
+		"$defs": object{
+			"screen": object{
+				"type": "object",
+				"properties": object{
+					"name": object{"type": "string", "minLength": 1},
+					"position": object{
+						"type": "object",
+						"properties": object{
+							"x": object{"type": "integer"},
+							"y": object{"type": "integer"},
+						},
+						"additionalProperties": false,
+					},
+					"size": object{
+						"type": "object",
+						"properties": object{
+							"width":  object{"type": "integer", "minimum": 0},
+							"height": object{"type": "integer", "minimum": 0},
+						},
+						"additionalProperties": false,
+					},
+					"interval": object{"type": "string", "description": "how long each tab of the screen is shown, e.g. '10s'"},
+					"tabs":     object{"$ref": "#/$defs/tabs"},
+				},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+			"tabs": object{
+				"type":  "array",
+				"items": object{"$ref": "#/$defs/tab"},
+			},
+			"tab": object{
+				"type": "object",
+				"properties": object{
+					"name":    object{"type": "string"},
+					"onError": object{"enum": []ErrorPolicy{OnErrorAbort, OnErrorSkip, OnErrorPlaceholder, OnErrorRetry}},
+					"zoom":    object{"type": "number", "exclusiveMinimum": 0},
+					"viewport": object{
+						"type": "object",
+						"properties": object{
+							"width":             object{"type": "integer", "minimum": 0},
+							"height":            object{"type": "integer", "minimum": 0},
+							"deviceScaleFactor": object{"type": "number", "minimum": 0},
+						},
+						"additionalProperties": false,
+					},
+					"userAgent":   object{"type": "string"},
+					"colorScheme": object{"enum": []ColorScheme{ColorSchemeLight, ColorSchemeDark}},
+					"headers":     object{"$ref": "#/$defs/secrets"},
+					"cookies": object{
+						"type":  "array",
+						"items": object{"$ref": "#/$defs/cookie"},
+					},
+					"localStorage":   object{"$ref": "#/$defs/secrets"},
+					"sessionStorage": object{"$ref": "#/$defs/secrets"},
+					"auth": object{
+						"type": "object",
+						"properties": object{
+							"username": object{"type": "string"},
+							"password": object{"$ref": "#/$defs/secret"},
+							"origin":   object{"type": "string", "format": "uri"},
+							"certificate": object{
+								"type": "object",
+								"properties": object{
+									"issuer":  object{"type": "string"},
+									"subject": object{"type": "string"},
+								},
+								"minProperties":        1,
+								"additionalProperties": false,
+							},
+						},
+						"additionalProperties": false,
+					},
+					"block": object{
+						"type":  "array",
+						"items": object{"type": "string", "minLength": 1},
+					},
+					"rewrite": object{
+						"type": "array",
+						"items": object{
+							"type": "object",
+							"properties": object{
+								"from": object{"type": "string", "minLength": 1},
+								"to":   object{"type": "string", "minLength": 1},
+							},
+							"required":             []string{"from", "to"},
+							"additionalProperties": false,
+						},
+					},
+					"layout": object{
+						"description": "show the pages of several panes side by side instead of running a script",
+						"type":        "object",
+						"properties": object{
+							"columns": object{"type": "integer", "minimum": 0},
+							"panes": object{
+								"type":     "array",
+								"minItems": 1,
+								"items":    object{"$ref": "#/$defs/pane"},
+							},
+						},
+						"required":             []string{"panes"},
+						"additionalProperties": false,
+					},
+					"image":  object{"$ref": "#/$defs/media", "description": "show a local image"},
+					"video":  object{"$ref": "#/$defs/media", "description": "play a local video in a loop"},
+					"pdf":    object{"$ref": "#/$defs/media", "description": "show the pages of a local PDF one after another"},
+					"folder": object{"$ref": "#/$defs/media", "description": "show the images and videos of a local folder one after another"},
+					"announce": object{
+						"description": "show a message written in Markdown or HTML, both being Go templates",
+						"type":        "object",
+						"properties": object{
+							"markdown": object{"type": "string"},
+							"html":     object{"type": "string"},
+							"data":     object{"type": "string", "format": "uri", "description": "URL of a JSON document the template gets as .Data"},
+							"refresh":  object{"type": "string", "description": "how often to render the announcement again, e.g. '1m'"},
+						},
+						"oneOf": []object{
+							{"required": []string{"markdown"}},
+							{"required": []string{"html"}},
+						},
+						"additionalProperties": false,
+					},
+					"script": object{"$ref": "#/$defs/steps"},
+				},
+				"not":                  anyTwo("script", "layout", "image", "video", "pdf", "folder", "announce"),
+				"additionalProperties": false,
+			},
+			"media": object{
+				"oneOf": []object{
+					{
+						"type":        "string",
+						"description": "a path, relative to the script",
+					},
+					{
+						"type": "object",
+						"properties": object{
+							"path":     object{"type": "string"},
+							"duration": object{"type": "string", "description": "how long a single image or video, or each file of a folder or page of a PDF is shown, e.g. '20s'"},
+						},
+						"required":             []string{"path"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"pane": object{
+				"type": "object",
+				"properties": object{
+					"name":   object{"type": "string"},
+					"reload": object{"type": "string", "description": "how often to reload the page, e.g. '5m'"},
+					"health": object{"$ref": "#/$defs/condition", "description": "run the script again if the condition does not hold"},
+					"script": object{"$ref": "#/$defs/steps"},
+				},
+				"required":             []string{"script"},
+				"additionalProperties": false,
+			},
+			"cookie": object{
+				"type": "object",
+				"properties": object{
+					"name":     object{"type": "string", "minLength": 1},
+					"value":    object{"$ref": "#/$defs/secret"},
+					"domain":   object{"type": "string"},
+					"path":     object{"type": "string"},
+					"secure":   object{"type": "boolean"},
+					"httpOnly": object{"type": "boolean"},
+				},
+				"required":             []string{"name", "value"},
+				"additionalProperties": false,
+			},
+			"secrets": object{
+				"type":                 "object",
+				"additionalProperties": object{"$ref": "#/$defs/secret"},
+			},
+			"steps": object{
+				// nothing is no steps
+				"type":  []string{"array", "null"},
+				"items": object{"$ref": "#/$defs/step"},
+			},
+			"step": object{
+				"oneOf": steps,
+			},
+			"strategy": object{
+				// the default is written as '' if the query looks like it has a prefix
+				"enum": []Strategy{ByDefault, ByCSS, ByXPath, ByID, ByText, BySearch},
+			},
+			"selector": object{
+				"oneOf": []object{
+					{
+						"type":        "string",
+						"description": "a query, optionally prefixed with the strategy, e.g. 'css:#login'",
+					},
+					{
+						"type": "object",
+						"properties": object{
+							"selector": object{"type": "string"},
+							"by":       object{"$ref": "#/$defs/strategy"},
+						},
+						"required":             []string{"selector"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"secret": object{
+				"oneOf": []object{
+					{
+						// a PIN is taken as written, e.g. with leading zeros
+						"type":        []string{"string", "number"},
+						"description": "a literal, or 'env:NAME', or 'file:PATH'",
+					},
+					exactly("value"),
+					exactly("env"),
+					exactly("file"),
+					exactly("encrypted", "keyFile"),
+				},
+			},
+			"condition": object{
+				"oneOf": []object{
+					withProperty(exactly("exists"), "exists", object{"$ref": "#/$defs/selector"}),
+					withProperty(exactly("url"), "url", object{"type": "string", "format": "regex"}),
+					exactly("text"),
+				},
+			},
+		},
+	}
+}
+
+// stepWithAction describes a step with the given action and its optional modifiers.
+func stepWithAction(action string, value object) object {
+	properties := object{
+		action:    value,
+		"timeout": object{"type": "string", "description": "a duration like '10s' or '1m30s'"},
+		"unless":  object{"$ref": "#/$defs/condition", "description": "skip the step if the condition holds"},
+	}
+
+	switch action {
+	case "use":
+		// a snippet has the timeouts of its own steps
+		delete(properties, "timeout")
+	case "if":
+		properties["then"] = object{"$ref": "#/$defs/steps"}
+		properties["else"] = object{"$ref": "#/$defs/steps"}
+	}
+
+	return object{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             []string{action},
+		"additionalProperties": false,
+	}
+}
+
+// anyTwo describes an object with at least two of the given properties.
+func anyTwo(keys ...string) object {
+	var pairs []object
+
+	for i, first := range keys {
+		for _, second := range keys[i+1:] {
+			pairs = append(pairs, object{"required": []string{first, second}})
+		}
+	}
+
+	return object{"anyOf": pairs}
+}
+
+// exactly describes an object with exactly the given string properties.
+func exactly(keys ...string) object {
+	properties := object{}
+
+	for _, key := range keys {
+		properties[key] = object{"type": "string"}
+	}
+
+	return object{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             keys,
+		"additionalProperties": false,
+	}
+}
+
+// withProperty replaces the schema of the given property.
+func withProperty(o object, key string, value object) object {
+	o["properties"].(object)[key] = value
+	return o
+}
@@ -0,0 +1,355 @@
+package script_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+	"uhlig.it/kiosk/script"
+)
+
+// The schema is written by hand, next to the parser. The examples of the
+// README and the scripts the parser tests accept keep the two from drifting.
+var _ = Describe("Schema and parser", func() {
+	var schema *schemaValidator
+
+	BeforeEach(func() {
+		raw, err := script.Schema()
+		Expect(err).ToNot(HaveOccurred())
+
+		schema = &schemaValidator{}
+		Expect(json.Unmarshal(raw, &schema.root)).To(Succeed())
+	})
+
+	It("agree on the examples of the README", func() {
+		examples := readmeExamples()
+		Expect(examples).ToNot(BeEmpty())
+
+		for i, example := range examples {
+			document, err := decodeDocument(example)
+			Expect(err).ToNot(HaveOccurred(), "example %v:\n%s", i+1, example)
+
+			// e.g. a modeline
+			if document == nil {
+				continue
+			}
+
+			Expect(schema.validate(document)).To(Succeed(), "example %v:\n%s", i+1, example)
+		}
+	})
+
+	It("agree on the scripts accepted in the tests of the parser", func() {
+		fixtures := acceptedFixtures()
+		Expect(fixtures).ToNot(BeEmpty())
+
+		for _, fixture := range fixtures {
+			document, err := decodeDocument(fixture.markup)
+			Expect(err).ToNot(HaveOccurred(), "%v:\n%s", fixture.position, fixture.markup)
+
+			// an empty script is accepted, e.g. a selector that YAML takes for a comment
+			if document == nil {
+				continue
+			}
+
+			Expect(schema.validate(document)).To(Succeed(), "%v:\n%s", fixture.position, fixture.markup)
+		}
+	})
+
+	DescribeTable("agree on rejecting",
+		func(markup string) {
+			_, err := script.Parse([]byte(markup))
+			Expect(err).To(HaveOccurred())
+
+			document, err := decodeDocument([]byte(markup))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema.validate(document)).ToNot(Succeed())
+		},
+		Entry("unknown key of a tab", `[ { name: a, colour: red } ]`),
+		Entry("step without an action", `[ { script: [ { timeout: 5s } ] } ]`),
+		Entry("step with two actions", `[ { script: [ { go: https://example.com, click: //a } ] } ]`),
+		Entry("type without a selector", `[ { script: [ { type: { value: x } } ] } ]`),
+		Entry("type with a value and a secret", `[ { script: [ { type: { selector: //input, value: x, secret: env:X } } ] } ]`),
+		Entry("tab with a script and an image", `[ { image: a.png, script: [ { go: https://example.com } ] } ]`),
+		Entry("unknown error policy", `[ { onError: ignore, script: [] } ]`),
+		Entry("unknown strategy", `[ { script: [ { wait: { selector: a, by: magic } } ] } ]`),
+		Entry("cookie without a value", `[ { cookies: [ { name: a } ] } ]`),
+		Entry("pane without a script", `[ { layout: { panes: [ { name: a } ] } } ]`),
+		Entry("screen without a name", `{ screens: [ { tabs: [] } ] }`),
+		Entry("unknown key of a script", `{ tabs: [], colour: red }`),
+	)
+})
+
+// readmeExamples are the scripts in the README.
+func readmeExamples() [][]byte {
+	markup, err := os.ReadFile("../README.markdown")
+	Expect(err).ToNot(HaveOccurred())
+
+	var examples [][]byte
+
+	for _, match := range regexp.MustCompile("(?ms)^```(?:yaml|toml|json)\n(.*?)^```").FindAllSubmatch(markup, -1) {
+		examples = append(examples, match[1])
+	}
+
+	return examples
+}
+
+type fixture struct {
+	position token.Position
+	markup   []byte
+}
+
+// acceptedFixtures are the string literals of the tests in this package that
+// the parser accepts as a whole script.
+func acceptedFixtures() []fixture {
+	files, err := filepath.Glob("*_test.go")
+	Expect(err).ToNot(HaveOccurred())
+
+	var fixtures []fixture
+	fset := token.NewFileSet()
+
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			literal, ok := n.(*ast.BasicLit)
+
+			if !ok || literal.Kind != token.STRING {
+				return true
+			}
+
+			markup, err := strconv.Unquote(literal.Value)
+			Expect(err).ToNot(HaveOccurred())
+
+			if _, err := script.Parse([]byte(markup)); err != nil {
+				return true
+			}
+
+			fixtures = append(fixtures, fixture{position: fset.Position(literal.Pos()), markup: []byte(markup)})
+
+			return true
+		})
+	}
+
+	return fixtures
+}
+
+// decodeDocument decodes a script like JSON does, which is what the schema describes.
+func decodeDocument(markup []byte) (any, error) {
+	var document any
+	var err error
+
+	if script.DetectFormat("", markup) == script.FormatTOML {
+		err = toml.Unmarshal(markup, &document)
+	} else {
+		err = yaml.Unmarshal(markup, &document)
+	}
+
+	if err != nil || document == nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &document)
+
+	return document, err
+}
+
+// schemaValidator checks documents against the keywords of JSON Schema that
+// the schema of scripts uses. Any other keyword is a failure, so that it is
+// noticed once the schema starts to use it.
+type schemaValidator struct {
+	root map[string]any
+}
+
+func (v *schemaValidator) validate(document any) error {
+	return v.check(v.root, document, "")
+}
+
+var annotations = []string{"$schema", "$id", "title", "description", "format"}
+
+func (v *schemaValidator) check(schema any, value any, path string) error {
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			return fmt.Errorf("%v: is not allowed", pathOrRoot(path))
+		}
+
+		return nil
+	}
+
+	for _, keyword := range slices.Sorted(maps.Keys(schema.(map[string]any))) {
+		argument := schema.(map[string]any)[keyword]
+
+		if slices.Contains(annotations, keyword) || keyword == "$defs" {
+			continue
+		}
+
+		if err := v.checkKeyword(keyword, argument, schema.(map[string]any), value, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *schemaValidator) checkKeyword(keyword string, argument any, schema map[string]any, value any, path string) error {
+	object, isObject := value.(map[string]any)
+	array, isArray := value.([]any)
+	text, isString := value.(string)
+	number, isNumber := value.(float64)
+
+	switch keyword {
+	case "$ref":
+		name, found := strings.CutPrefix(argument.(string), "#/$defs/")
+		Expect(found).To(BeTrue(), "reference %v", argument)
+
+		return v.check(v.root["$defs"].(map[string]any)[name], value, path)
+	case "type":
+		types, ok := argument.([]any)
+
+		if !ok {
+			types = []any{argument}
+		}
+
+		for _, t := range types {
+			if hasType(value, t.(string)) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%v: %#v is not of type %v", pathOrRoot(path), value, argument)
+	case "enum":
+		if !slices.ContainsFunc(argument.([]any), func(allowed any) bool { return reflect.DeepEqual(allowed, value) }) {
+			return fmt.Errorf("%v: %#v is not one of %v", pathOrRoot(path), value, argument)
+		}
+	case "properties":
+		for key, property := range argument.(map[string]any) {
+			if child, found := object[key]; isObject && found {
+				if err := v.check(property, child, path+"/"+key); err != nil {
+					return err
+				}
+			}
+		}
+	case "additionalProperties":
+		properties, _ := schema["properties"].(map[string]any)
+
+		for key, child := range object {
+			if _, known := properties[key]; !known {
+				if err := v.check(argument, child, path+"/"+key); err != nil {
+					return err
+				}
+			}
+		}
+	case "required":
+		for _, key := range argument.([]any) {
+			if _, found := object[key.(string)]; isObject && !found {
+				return fmt.Errorf("%v: '%v' is missing", pathOrRoot(path), key)
+			}
+		}
+	case "minProperties":
+		if isObject && len(object) < int(argument.(float64)) {
+			return fmt.Errorf("%v: has less than %v properties", pathOrRoot(path), argument)
+		}
+	case "items":
+		for i, item := range array {
+			if err := v.check(argument, item, fmt.Sprintf("%v/%v", path, i)); err != nil {
+				return err
+			}
+		}
+	case "minItems":
+		if isArray && len(array) < int(argument.(float64)) {
+			return fmt.Errorf("%v: has less than %v items", pathOrRoot(path), argument)
+		}
+	case "minLength":
+		if isString && len(text) < int(argument.(float64)) {
+			return fmt.Errorf("%v: is shorter than %v", pathOrRoot(path), argument)
+		}
+	case "minimum":
+		if isNumber && number < argument.(float64) {
+			return fmt.Errorf("%v: is less than %v", pathOrRoot(path), argument)
+		}
+	case "exclusiveMinimum":
+		if isNumber && number <= argument.(float64) {
+			return fmt.Errorf("%v: is not more than %v", pathOrRoot(path), argument)
+		}
+	case "oneOf":
+		var errs []string
+
+		for _, alternative := range argument.([]any) {
+			if err := v.check(alternative, value, path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		if matches := len(argument.([]any)) - len(errs); matches != 1 {
+			return fmt.Errorf("%v: matches %v instead of one alternative: %v", pathOrRoot(path), matches, strings.Join(errs, "; "))
+		}
+	case "anyOf":
+		var errs []string
+
+		for _, alternative := range argument.([]any) {
+			if err := v.check(alternative, value, path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		if len(errs) == len(argument.([]any)) {
+			return fmt.Errorf("%v: matches no alternative: %v", pathOrRoot(path), strings.Join(errs, "; "))
+		}
+	case "not":
+		if v.check(argument, value, path) == nil {
+			return fmt.Errorf("%v: matches what is not allowed", pathOrRoot(path))
+		}
+	default:
+		Fail(fmt.Sprintf("the keyword '%v' of the schema is not supported by the tests", keyword))
+	}
+
+	return nil
+}
+
+func hasType(value any, t string) bool {
+	switch value := value.(type) {
+	case map[string]any:
+		return t == "object"
+	case []any:
+		return t == "array"
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || t == "integer" && value == math.Trunc(value)
+	case nil:
+		return t == "null"
+	default:
+		return false
+	}
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
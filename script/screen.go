@@ -0,0 +1,66 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Screen is a browser window with a rotation of tabs of its own, e.g. on
+// another display. The tabs of a script without screens are on the default
+// screen, which has no name.
+type Screen struct {
+	Name string
+	// Position is where the top left corner of the window is on the desktop.
+	Position Position
+	// Size of the window; zero keeps the default of the browser.
+	Size Size
+	// Interval overrides how long each tab of the screen is shown.
+	Interval time.Duration
+	Tabs     []*Tab
+}
+
+type Position struct {
+	X int64
+	Y int64
+}
+
+type Size struct {
+	Width  int64
+	Height int64
+}
+
+func (s *Screen) IsDefault() bool {
+	return s.Name == ""
+}
+
+// isPlain tells whether the screen has nothing but tabs.
+func (s *Screen) isPlain() bool {
+	return s.Position == (Position{}) && s.Size == (Size{}) && s.Interval == 0
+}
+
+func (s *Screen) Validate() error {
+	// The name of a screen is part of the path of its browser profile.
+	if strings.ContainsAny(s.Name, `/\`) || s.Name == "." || s.Name == ".." {
+		return fmt.Errorf("'%v' is not a valid name for a screen", s.Name)
+	}
+
+	if s.Size.Width < 0 || s.Size.Height < 0 {
+		return errors.New("width and height of a screen must not be negative")
+	}
+
+	if s.Interval < 0 {
+		return errors.New("interval of a screen must not be negative")
+	}
+
+	return nil
+}
+
+func (s *Screen) String() string {
+	if s.IsDefault() {
+		return "the default screen"
+	}
+
+	return fmt.Sprintf("screen '%v'", s.Name)
+}
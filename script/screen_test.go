@@ -0,0 +1,130 @@
+package script_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Screens", func() {
+	const markup = `
+tabs:
+  - name: Touch
+    script:
+      - go: https://example.com/touch
+screens:
+  - name: wall
+    position: { x: 800, y: 0 }
+    size: { width: 1920, height: 1080 }
+    interval: 30s
+    tabs:
+      - name: Dashboard
+        script:
+          - go: https://example.com/dashboard
+      - name: Weather
+        script:
+          - go: https://example.com/weather
+`
+
+	It("parses", func() {
+		screens, err := script.NewParser().ParseScreens([]byte(markup))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(screens).To(HaveLen(2))
+
+		Expect(screens[0].IsDefault()).To(BeTrue())
+		Expect(screens[0].Tabs).To(HaveLen(1))
+		Expect(screens[0].Tabs[0].Name).To(Equal("Touch"))
+
+		Expect(screens[1].Name).To(Equal("wall"))
+		Expect(screens[1].Position).To(Equal(script.Position{X: 800, Y: 0}))
+		Expect(screens[1].Size).To(Equal(script.Size{Width: 1920, Height: 1080}))
+		Expect(screens[1].Interval).To(Equal(30 * time.Second))
+		Expect(screens[1].Tabs).To(HaveLen(2))
+		Expect(screens[1].Tabs[1].Name).To(Equal("Weather"))
+	})
+
+	It("has the tabs of all screens when parsing tabs", func() {
+		tabs, err := script.Parse([]byte(markup))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(3))
+		Expect(tabs[2].Name).To(Equal("Weather"))
+	})
+
+	It("has only the default screen without screens", func() {
+		screens, err := script.NewParser().ParseScreens([]byte(`
+- name: Touch
+  script:
+    - go: https://example.com/touch
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(screens).To(HaveLen(1))
+		Expect(screens[0].IsDefault()).To(BeTrue())
+		Expect(screens[0].Tabs).To(HaveLen(1))
+	})
+
+	It("leaves out the default screen if it has no tabs", func() {
+		screens, err := script.NewParser().ParseScreens([]byte(`
+screens:
+  - name: wall
+    tabs:
+      - script:
+          - go: https://example.com/dashboard
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(screens).To(HaveLen(1))
+		Expect(screens[0].Name).To(Equal("wall"))
+	})
+
+	It("round-trips", func() {
+		screens, err := script.NewParser().ParseScreens([]byte(markup))
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := script.MarshalScreens(screens)
+		Expect(err).ToNot(HaveOccurred())
+
+		reparsed, err := script.NewParser().ParseScreens(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reparsed).To(Equal(screens))
+	})
+
+	It("writes a script without screens like its tabs", func() {
+		tabs, err := script.Parse([]byte(markup))
+		Expect(err).ToNot(HaveOccurred())
+
+		expected, err := script.Marshal(tabs[:1])
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(script.MarshalScreens([]*script.Screen{{Tabs: tabs[:1]}})).To(Equal(expected))
+	})
+
+	DescribeTable("rejected",
+		func(screen string, expected string) {
+			screens, err := script.NewParser().ParseScreens([]byte(`
+screens:
+  - ` + screen + `
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(screens).To(BeEmpty())
+		},
+		Entry("screen without a name", `{ tabs: [] }`, "3:5: a screen needs a name"),
+		Entry("screen defined twice", `{ name: wall }
+  - { name: wall }`, "4:13: screen 'wall' is defined more than once"),
+		Entry("position that is not a map", `{ name: wall, position: 800 }`, "3:29: unable to parse '800' as position"),
+		Entry("position that is not a whole number", `{ name: wall, position: { x: 0.5 } }`, "3:34: 'x' of a position must be a whole number"),
+		Entry("unknown key of a size", `{ name: wall, size: { depth: 3 } }`, "3:27: 'depth' is not a known key for a size"),
+		Entry("name with a slash", `{ name: ../wall }`, "3:5: '../wall' is not a valid name for a screen"),
+		Entry("name with a backslash", `{ name: a\b }`, "3:5: 'a\\b' is not a valid name for a screen"),
+		Entry("name of the parent directory", `{ name: .. }`, "3:5: '..' is not a valid name for a screen"),
+		Entry("negative size", `{ name: wall, size: { width: -1 } }`, "3:5: width and height of a screen must not be negative"),
+		Entry("unparsable interval", `{ name: wall, interval: soon }`, `3:29: unable to parse 'soon' as interval: time: invalid duration "soon"`),
+		Entry("unknown key", `{ name: wall, display: 2 }`, "3:19: 'display' is not a known key for a screen"),
+		Entry("tabs that are not a list", `{ name: wall, tabs: dashboard }`, "3:25: unable to parse 'dashboard' as list of tabs"),
+	)
+
+	It("rejects screens that are not a list", func() {
+		_, err := script.NewParser().ParseScreens([]byte(`screens: { name: wall }`))
+		Expect(err).To(MatchError("1:10: unable to parse 'map[name:wall]' as list of screens"))
+	})
+})
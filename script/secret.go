@@ -0,0 +1,243 @@
+package script
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretsDirectory is where relative 'file:' secrets are looked up. It
+// defaults to the credentials directory passed by systemd, or the location
+// Docker mounts secrets to.
+var SecretsDirectory = defaultSecretsDirectory()
+
+type secretSource string
+
+const (
+	secretLiteral   secretSource = ""
+	secretEnv       secretSource = "env"
+	secretFile      secretSource = "file"
+	secretEncrypted secretSource = "encrypted"
+)
+
+// Secret refers to a value that must not show up in logs or any other output.
+// It is resolved only when it is needed.
+type Secret struct {
+	source  secretSource
+	ref     string
+	keyFile string
+}
+
+// ParseSecret reads a reference like "env:NAME" or "file:/run/secrets/x".
+// Anything else is taken literally.
+func ParseSecret(s string) Secret {
+	prefix, ref, found := strings.Cut(s, ":")
+
+	if found {
+		switch secretSource(prefix) {
+		case secretEnv, secretFile:
+			return Secret{source: secretSource(prefix), ref: ref}
+		}
+	}
+
+	return Secret{ref: s}
+}
+
+// EncryptedSecret refers to a file encrypted with EncryptSecret using the key in keyFile.
+func EncryptedSecret(path string, keyFile string) Secret {
+	return Secret{source: secretEncrypted, ref: path, keyFile: keyFile}
+}
+
+func (s Secret) IsZero() bool {
+	return s.source == secretLiteral && s.ref == ""
+}
+
+// Resolve returns the actual value. Errors never contain the value itself.
+func (s Secret) Resolve() (string, error) {
+	switch s.source {
+	case secretEnv:
+		value, found := os.LookupEnv(s.ref)
+
+		if !found {
+			return "", fmt.Errorf("environment variable %v is not set", s.ref)
+		}
+
+		return value, nil
+	case secretFile:
+		content, err := os.ReadFile(s.path(s.ref))
+
+		if err != nil {
+			return "", fmt.Errorf("could not read secret: %w", err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	case secretEncrypted:
+		return s.decrypt()
+	default:
+		return s.ref, nil
+	}
+}
+
+func (s Secret) Validate() error {
+	switch s.source {
+	case secretEnv:
+		if s.ref == "" {
+			return errors.New("name of the environment variable must not be empty")
+		}
+	case secretFile:
+		if s.ref == "" {
+			return errors.New("path of the secret file must not be empty")
+		}
+	case secretEncrypted:
+		if s.ref == "" {
+			return errors.New("path of the encrypted secret must not be empty")
+		}
+
+		if s.keyFile == "" {
+			return errors.New("path of the key file must not be empty")
+		}
+	default:
+		if s.ref == "" {
+			return errors.New("secret must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// String describes where the secret comes from, but never its value.
+func (s Secret) String() string {
+	switch s.source {
+	case secretEnv:
+		return fmt.Sprintf("the secret from environment variable %v", s.ref)
+	case secretFile:
+		return fmt.Sprintf("the secret from %v", s.path(s.ref))
+	case secretEncrypted:
+		return fmt.Sprintf("the encrypted secret from %v", s.path(s.ref))
+	default:
+		return "the secret"
+	}
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(SecretsDirectory, p)
+}
+
+func (s Secret) decrypt() (string, error) {
+	key, err := readKey(s.path(s.keyFile))
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := os.ReadFile(s.path(s.ref))
+
+	if err != nil {
+		return "", fmt.Errorf("could not read encrypted secret: %w", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+
+	if err != nil {
+		return "", fmt.Errorf("could not decode encrypted secret %v: %w", s.ref, err)
+	}
+
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted secret %v is too short", s.ref)
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt secret %v; is it the right key?", s.ref)
+	}
+
+	return string(plaintext), nil
+}
+
+// EncryptSecret produces the content of a file that can be referred to by
+// EncryptedSecret. The key is 32 bytes, either raw or hex-encoded.
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(decodeKey(key))
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func readKey(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file: %w", err)
+	}
+
+	return decodeKey(content), nil
+}
+
+func decodeKey(key []byte) []byte {
+	trimmed := strings.TrimSpace(string(key))
+
+	if decoded, err := hex.DecodeString(trimmed); err == nil && len(decoded) == 32 {
+		return decoded
+	}
+
+	// a raw key file may end with a newline, which is not part of the key
+	if len(key) == 33 && key[32] == '\n' {
+		return key[:32]
+	}
+
+	return key
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, not %v", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func defaultSecretsDirectory() string {
+	if dir, found := os.LookupEnv("CREDENTIALS_DIRECTORY"); found {
+		return dir
+	}
+
+	return "/run/secrets"
+}
@@ -0,0 +1,238 @@
+package script_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Secret", func() {
+	var secret script.Secret
+	var value string
+	var err error
+
+	JustBeforeEach(func() {
+		value, err = secret.Resolve()
+	})
+
+	Context("literal", func() {
+		BeforeEach(func() {
+			secret = script.ParseSecret("s3cret")
+		})
+
+		It("resolves to itself", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal("s3cret"))
+		})
+
+		It("does not reveal itself", func() {
+			Expect(secret.String()).ToNot(ContainSubstring("s3cret"))
+			Expect(fmt.Sprintf("%v %+v %#v", secret, secret, secret)).ToNot(ContainSubstring("s3cret"))
+		})
+	})
+
+	Context("environment variable", func() {
+		BeforeEach(func() {
+			secret = script.ParseSecret("env:KIOSK_TEST_SECRET")
+		})
+
+		Context("set", func() {
+			BeforeEach(func() {
+				GinkgoT().Setenv("KIOSK_TEST_SECRET", "from-env")
+			})
+
+			It("resolves to the value of the variable", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal("from-env"))
+			})
+
+			It("does not reveal itself", func() {
+				Expect(secret.String()).ToNot(ContainSubstring("from-env"))
+			})
+		})
+
+		Context("not set", func() {
+			It("fails", func() {
+				Expect(err).To(MatchError("environment variable KIOSK_TEST_SECRET is not set"))
+			})
+		})
+	})
+
+	Context("file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "password"), []byte("from-file\n"), 0600)).To(Succeed())
+		})
+
+		Context("absolute path", func() {
+			BeforeEach(func() {
+				secret = script.ParseSecret("file:" + filepath.Join(dir, "password"))
+			})
+
+			It("resolves to the content of the file without trailing newline", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal("from-file"))
+			})
+		})
+
+		Context("relative path", func() {
+			BeforeEach(func() {
+				DeferCleanup(func(previous string) { script.SecretsDirectory = previous }, script.SecretsDirectory)
+				script.SecretsDirectory = dir
+				secret = script.ParseSecret("file:password")
+			})
+
+			It("resolves against the secrets directory", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal("from-file"))
+			})
+		})
+
+		Context("missing file", func() {
+			BeforeEach(func() {
+				secret = script.ParseSecret("file:" + filepath.Join(dir, "missing"))
+			})
+
+			It("fails", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("encrypted file", func() {
+		var dir string
+		var key []byte
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			key = []byte(hex.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+			Expect(os.WriteFile(filepath.Join(dir, "key"), key, 0600)).To(Succeed())
+
+			encrypted, err := script.EncryptSecret(key, "from-encrypted-file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, "password.enc"), []byte(encrypted), 0600)).To(Succeed())
+
+			secret = script.EncryptedSecret(filepath.Join(dir, "password.enc"), filepath.Join(dir, "key"))
+		})
+
+		It("resolves to the decrypted content", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal("from-encrypted-file"))
+		})
+
+		Context("raw key with a trailing newline", func() {
+			BeforeEach(func() {
+				raw := []byte("0123456789abcdef0123456789abcdef")
+				Expect(os.WriteFile(filepath.Join(dir, "key"), append(raw, '\n'), 0600)).To(Succeed())
+
+				encrypted, err := script.EncryptSecret(raw, "from-encrypted-file")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(dir, "password.enc"), []byte(encrypted), 0600)).To(Succeed())
+			})
+
+			It("resolves to the decrypted content", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal("from-encrypted-file"))
+			})
+		})
+
+		Context("wrong key", func() {
+			BeforeEach(func() {
+				Expect(os.WriteFile(filepath.Join(dir, "key"), []byte("fedcba9876543210fedcba9876543210"), 0600)).To(Succeed())
+			})
+
+			It("fails", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).ToNot(ContainSubstring("from-encrypted-file"))
+			})
+		})
+	})
+
+	Context("in a script", func() {
+		var tabs []*script.Tab
+
+		JustBeforeEach(func() {
+			tabs, err = script.Parse([]byte(`
+- name: Secrets
+  script:
+    - type:
+        xpath: user
+        secret: env:USER_NAME
+    - type:
+        xpath: password
+        secret:
+          file: /run/secrets/password
+    - type:
+        xpath: pin
+        secret:
+          encrypted: pin.enc
+          keyFile: pin.key
+`))
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs[0].Steps).To(HaveLen(3))
+		})
+
+		It("presents the steps without revealing anything", func() {
+			Expect(tabs[0].Steps[0].String()).To(Equal("type the secret from environment variable USER_NAME into the element addressed by 'user'"))
+			Expect(tabs[0].Steps[1].String()).To(Equal("type the secret from /run/secrets/password into the element addressed by 'password'"))
+			Expect(tabs[0].Steps[2].String()).To(HavePrefix("type the encrypted secret from "))
+		})
+	})
+
+	Context("number in a script", func() {
+		It("is a literal", func() {
+			tabs, err := script.Parse([]byte(`
+- name: Secrets
+  script:
+    - type:
+        xpath: pin
+        secret: 0042
+`))
+			Expect(err).ToNot(HaveOccurred())
+
+			value, err := tabs[0].Steps[0].(*script.Type).Secret.Resolve()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal("0042"))
+		})
+	})
+
+	Context("invalid in a script", func() {
+		It("is not revealed", func() {
+			_, err := script.Parse([]byte(`
+- name: Secrets
+  auth:
+    username: jdoe
+    password:
+      value: [ hunter2 ]
+  script:
+    - go: https://example.com
+`))
+			Expect(err).To(MatchError("6:14: tab 'Secrets': 'value' of password of auth must be a string or a number"))
+			Expect(err.Error()).ToNot(ContainSubstring("hunter2"))
+		})
+	})
+
+	Context("incomplete reference in a script", func() {
+		It("does not parse", func() {
+			_, err := script.Parse([]byte(`
+- name: Secrets
+  script:
+    - type:
+        xpath: pin
+        secret:
+          encrypted: pin.enc
+`))
+			Expect(err).To(MatchError("7:11: tab 'Secrets', step 1: secret of a Type step needs either 'value', 'env', 'file', or 'encrypted' with 'keyFile'"))
+		})
+	})
+})
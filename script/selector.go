@@ -0,0 +1,120 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Strategy determines how the query of a Selector is resolved to a DOM node.
+type Strategy string
+
+const (
+	// ByDefault leaves the interpretation of the query to chromedp, which
+	// accepts XPath, CSS selectors and plain text alike.
+	ByDefault Strategy = ""
+	ByCSS     Strategy = "css"
+	ByXPath   Strategy = "xpath"
+	ByID      Strategy = "id"
+	ByText    Strategy = "text"
+	BySearch  Strategy = "search"
+)
+
+var strategies = []Strategy{ByCSS, ByXPath, ByID, ByText, BySearch}
+
+func (s Strategy) Validate() error {
+	if s == ByDefault {
+		return nil
+	}
+
+	for _, known := range strategies {
+		if s == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("'%v' is not a known selector strategy", string(s))
+}
+
+// Selector addresses an element of the page.
+type Selector struct {
+	Query string   `yaml:"selector"`
+	By    Strategy `yaml:"by"`
+}
+
+// ParseSelector reads a selector that may carry its strategy as prefix,
+// e.g. "css:#login" or "text:Sign in". Anything without a known prefix is
+// taken as-is with the default strategy.
+func ParseSelector(s string) Selector {
+	prefix, query, found := strings.Cut(s, ":")
+
+	if found {
+		for _, known := range strategies {
+			if Strategy(prefix) == known {
+				return Selector{Query: query, By: known}
+			}
+		}
+	}
+
+	return Selector{Query: s}
+}
+
+func (s Selector) QueryOptions() []chromedp.QueryOption {
+	switch s.By {
+	case ByCSS:
+		return []chromedp.QueryOption{chromedp.ByQuery}
+	case ByID:
+		return []chromedp.QueryOption{chromedp.ByID}
+	case ByXPath, ByText, BySearch:
+		return []chromedp.QueryOption{chromedp.BySearch}
+	default:
+		return nil
+	}
+}
+
+// expression is the query as it is handed to chromedp.
+func (s Selector) expression() string {
+	if s.By == ByText {
+		// chromedp's search would match the text node itself, which cannot be
+		// clicked or typed into. Address the element containing the text instead.
+		return fmt.Sprintf("//*[text()[contains(., %v)]]", xpathLiteral(s.Query))
+	}
+
+	return s.Query
+}
+
+func (s Selector) String() string {
+	if s.By == ByDefault {
+		return fmt.Sprintf("'%v'", s.Query)
+	}
+
+	return fmt.Sprintf("%v '%v'", string(s.By), s.Query)
+}
+
+func (s Selector) Validate() error {
+	if s.Query == "" {
+		return errors.New("value must not be empty")
+	}
+
+	return s.By.Validate()
+}
+
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+
+	parts := strings.Split(s, `"`)
+
+	for i, part := range parts {
+		parts[i] = `"` + part + `"`
+	}
+
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
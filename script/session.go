@@ -0,0 +1,347 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie is set in the browser before the steps of a tab run.
+type Cookie struct {
+	Name  string `yaml:"name"`
+	Value Secret `yaml:"value"`
+	// Domain defaults to the host of the URL of the tab.
+	Domain   string `yaml:"domain"`
+	Path     string `yaml:"path"`
+	Secure   bool   `yaml:"secure"`
+	HTTPOnly bool   `yaml:"httpOnly"`
+}
+
+func (c Cookie) Validate() error {
+	if c.Name == "" {
+		return errors.New("name of a cookie must not be empty")
+	}
+
+	if c.Value.IsZero() {
+		return fmt.Errorf("value of cookie '%v' must not be empty", c.Name)
+	}
+
+	return c.Value.Validate()
+}
+
+func (c Cookie) String() string {
+	return fmt.Sprintf("cookie '%v' with %v", c.Name, c.Value)
+}
+
+// sessions remembers what Session set up in which browser tab, so that it
+// can be undone when the browser tab is set up again, maybe for another Tab.
+var sessions = struct {
+	sync.Mutex
+	byTarget map[target.ID]*session
+}{byTarget: make(map[target.ID]*session)}
+
+type session struct {
+	storageScriptID page.ScriptIdentifier
+	stopIntercept   context.CancelFunc
+}
+
+// Session sets the headers, cookies and web storage of the tab, and answers
+// authentication challenges. It needs to run before the first step, so that
+// the first request carries them.
+func (n *Tab) Session() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		previous := resetSession(ctx)
+		current := &session{}
+
+		defer func() {
+			sessions.Lock()
+			sessions.byTarget[chromedp.FromContext(ctx).Target.TargetID] = current
+			sessions.Unlock()
+		}()
+
+		if previous.stopIntercept != nil {
+			previous.stopIntercept()
+
+			if err := fetch.Disable().Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		if previous.storageScriptID != "" {
+			if err := page.RemoveScriptToEvaluateOnNewDocument(previous.storageScriptID).Do(ctx); err != nil {
+				return fmt.Errorf("could not remove previous storage: %w", err)
+			}
+		}
+
+		for _, cookie := range n.Cookies {
+			value, err := cookie.Value.Resolve()
+
+			if err != nil {
+				return fmt.Errorf("could not resolve cookie '%v': %w", cookie.Name, err)
+			}
+
+			params := network.SetCookie(cookie.Name, value).
+				WithPath(cookie.Path).
+				WithSecure(cookie.Secure).
+				WithHTTPOnly(cookie.HTTPOnly)
+
+			if cookie.Domain != "" {
+				params = params.WithDomain(cookie.Domain)
+			} else {
+				params = params.WithURL(n.URL())
+			}
+
+			if err := params.Do(ctx); err != nil {
+				return fmt.Errorf("could not set cookie '%v': %w", cookie.Name, err)
+			}
+		}
+
+		if len(n.LocalStorage) > 0 || len(n.SessionStorage) > 0 {
+			source, err := n.storageScript()
+
+			if err != nil {
+				return err
+			}
+
+			current.storageScriptID, err = page.AddScriptToEvaluateOnNewDocument(source).Do(ctx)
+
+			if err != nil {
+				return fmt.Errorf("could not set storage: %w", err)
+			}
+		}
+
+		if n.answersAuth() || len(n.Block) > 0 || len(n.Rewrite) > 0 || len(n.Headers) > 0 {
+			headers := make(map[string]string)
+
+			for name, value := range n.Headers {
+				resolved, err := value.Resolve()
+
+				if err != nil {
+					return fmt.Errorf("could not resolve header '%v': %w", name, err)
+				}
+
+				headers[name] = resolved
+			}
+
+			stop, err := n.intercept(ctx, headers)
+
+			if err != nil {
+				return fmt.Errorf("could not intercept requests: %w", err)
+			}
+
+			current.stopIntercept = stop
+		}
+
+		return nil
+	})
+}
+
+func resetSession(ctx context.Context) *session {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	targetID := chromedp.FromContext(ctx).Target.TargetID
+	previous, found := sessions.byTarget[targetID]
+	delete(sessions.byTarget, targetID)
+
+	if !found {
+		return &session{}
+	}
+
+	return previous
+}
+
+// EndSession forgets what Session set up in the browser tab of ctx, once the
+// browser tab is closed.
+func EndSession(ctx context.Context) {
+	if c := chromedp.FromContext(ctx); c == nil || c.Target == nil {
+		return
+	}
+
+	if previous := resetSession(ctx); previous.stopIntercept != nil {
+		previous.stopIntercept()
+	}
+}
+
+func (n *Tab) answersAuth() bool {
+	return n.Auth != nil && n.Auth.Username != ""
+}
+
+// intercept pauses every request of the tab until it is dealt with, which
+// is necessary to answer authentication challenges, to apply the rules, and
+// to add the headers to the requests to the origins of the tab only.
+func (n *Tab) intercept(ctx context.Context, headers map[string]string) (context.CancelFunc, error) {
+	interceptCtx, stop := context.WithCancel(ctx)
+	origins := n.headerOrigins()
+
+	var mutex sync.Mutex
+	challenged := make(map[fetch.RequestID]bool)
+
+	// the listener must not block, so requests are continued in the background
+	chromedp.ListenTarget(interceptCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			blocked, rewritten := n.route(ev.Request.URL)
+
+			if blocked {
+				go continueRequest(interceptCtx, fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient))
+				return
+			}
+
+			continued := fetch.ContinueRequest(ev.RequestID)
+			requestURL := ev.Request.URL
+
+			if rewritten != "" {
+				continued = continued.WithURL(rewritten)
+				requestURL = rewritten
+			}
+
+			if len(headers) > 0 && slices.Contains(origins, originOf(requestURL)) {
+				continued = continued.WithHeaders(withHeaders(ev.Request.Headers, headers))
+			}
+
+			go continueRequest(interceptCtx, continued)
+		case *fetch.EventAuthRequired:
+			mutex.Lock()
+			rejected := challenged[ev.RequestID]
+			challenged[ev.RequestID] = true
+			mutex.Unlock()
+
+			go continueRequest(interceptCtx, fetch.ContinueWithAuth(ev.RequestID, n.respond(ev.AuthChallenge, rejected)))
+		}
+	})
+
+	if err := fetch.Enable().WithHandleAuthRequests(n.answersAuth()).Do(ctx); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return stop, nil
+}
+
+// headerOrigins are the origins the headers of the tab are sent to: the one
+// of its first 'go' step, and the ones of its panes.
+func (n *Tab) headerOrigins() []string {
+	var origins []string
+
+	urls := []string{n.URL()}
+
+	if n.Layout != nil {
+		for _, pane := range n.Layout.Panes {
+			urls = append(urls, pane.URL())
+		}
+	}
+
+	for _, rawURL := range urls {
+		if o := originOf(rawURL); o != "" && !slices.Contains(origins, o) {
+			origins = append(origins, o)
+		}
+	}
+
+	return origins
+}
+
+// withHeaders returns the headers of a request with the given ones added,
+// replacing those of the same name.
+func withHeaders(request network.Headers, headers map[string]string) []*fetch.HeaderEntry {
+	var entries []*fetch.HeaderEntry
+	replaced := make(map[string]bool)
+
+	for name := range headers {
+		replaced[strings.ToLower(name)] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(request)) {
+		if !replaced[strings.ToLower(name)] {
+			entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(request[name])})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: headers[name]})
+	}
+
+	return entries
+}
+
+// continueRequest ignores errors, as there is no one to tell. They happen
+// e.g. when the tab is closed while the request was paused.
+func continueRequest(ctx context.Context, action chromedp.Action) {
+	_ = chromedp.Run(ctx, action)
+}
+
+// storageScript fills the web storage of every page from the origin of the
+// tab, before the scripts of the page run. Storage exists only per origin,
+// so it cannot be filled before the first page of the origin is loaded.
+func (n *Tab) storageScript() (string, error) {
+	origin, err := origin(n.URL())
+
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "if (location.origin === %v) {\n", jsString(origin))
+
+	for _, storage := range []struct {
+		name  string
+		items map[string]Secret
+	}{{"localStorage", n.LocalStorage}, {"sessionStorage", n.SessionStorage}} {
+		for _, key := range slices.Sorted(maps.Keys(storage.items)) {
+			value, err := storage.items[key].Resolve()
+
+			if err != nil {
+				return "", fmt.Errorf("could not resolve %v item '%v': %w", storage.name, key, err)
+			}
+
+			fmt.Fprintf(&b, "  %v.setItem(%v, %v);\n", storage.name, jsString(key), jsString(value))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String(), nil
+}
+
+// origin returns the origin of the absolute URL as the browser reports it,
+// with the host in lower case and without the default port of the scheme.
+func origin(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("storage needs the tab to go to an absolute URL first, not '%v'", rawURL)
+	}
+
+	host := strings.ToLower(u.Host)
+
+	if port := u.Port(); u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+
+	return u.Scheme + "://" + host, nil
+}
+
+// originOf returns the origin of the absolute URL; empty for other URLs.
+func originOf(rawURL string) string {
+	o, _ := origin(rawURL)
+
+	return o
+}
+
+func jsString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
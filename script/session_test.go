@@ -0,0 +1,142 @@
+package script_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Tab session", func() {
+	It("parses", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Dashboard
+  headers:
+    Authorization: env:DASHBOARD_TOKEN
+    X-Kiosk: lobby
+  cookies:
+    - name: session
+      value: file:dashboard-session
+      secure: true
+      httpOnly: true
+    - name: theme
+      value: dark
+      domain: .example.com
+      path: /dashboards
+  localStorage:
+    token: { env: DASHBOARD_TOKEN }
+  sessionStorage:
+    mode: { value: "env:literal" }
+  script:
+    - go: https://example.com/dashboard
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+		Expect(tabs[0].Headers).To(Equal(map[string]script.Secret{
+			"Authorization": script.ParseSecret("env:DASHBOARD_TOKEN"),
+			"X-Kiosk":       script.ParseSecret("lobby"),
+		}))
+		Expect(tabs[0].Cookies).To(Equal([]script.Cookie{
+			{Name: "session", Value: script.ParseSecret("file:dashboard-session"), Secure: true, HTTPOnly: true},
+			{Name: "theme", Value: script.ParseSecret("dark"), Domain: ".example.com", Path: "/dashboards"},
+		}))
+		Expect(tabs[0].LocalStorage).To(Equal(map[string]script.Secret{"token": script.ParseSecret("env:DASHBOARD_TOKEN")}))
+		Expect(tabs[0].SessionStorage).To(HaveKey("mode"))
+		Expect(tabs[0].SessionStorage["mode"].Resolve()).To(Equal("env:literal"))
+	})
+
+	It("never shows the values", func() {
+		cookie := script.Cookie{Name: "session", Value: script.ParseSecret("s3cr3t")}
+		Expect(cookie.String()).ToNot(ContainSubstring("s3cr3t"))
+	})
+
+	DescribeTable("rejected",
+		func(setting string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Session
+  ` + setting + `
+  script:
+    - go: https://example.com
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("headers that are not a map", `headers: [ a, b ]`, "3:12: tab 'Session': unable to parse '[a b]' as map of headers"),
+		Entry("header that is not a scalar", `headers: { X-Count: [ 42 ] }`, "3:23: tab 'Session': header 'X-Count' must be a string, a number or a map"),
+		Entry("empty header", `headers: { X-Empty: "" }`, "3:23: tab 'Session': header 'X-Empty': secret must not be empty"),
+		Entry("unknown secret source", `headers: { X-Key: { vault: x } }`, "3:21: tab 'Session': header 'X-Key' needs either 'value', 'env', 'file', or 'encrypted' with 'keyFile'"),
+		Entry("cookie value that is not a scalar", `cookies: [ { name: x, value: [ hunter2 ] } ]`, "3:32: tab 'Session': value of a cookie must be a string, a number or a map"),
+		Entry("cookies that are not a list", `cookies: session`, "3:12: tab 'Session': unable to parse 'session' as list of cookies"),
+		Entry("cookie without a name", `cookies: [ { value: x } ]`, "3:14: tab 'Session': name of a cookie must not be empty"),
+		Entry("cookie without a value", `cookies: [ { name: x } ]`, "3:14: tab 'Session': value of cookie 'x' must not be empty"),
+		Entry("cookie flag that is not a boolean", `cookies: [ { name: x, value: y, secure: maybe } ]`, "3:43: tab 'Session': unable to convert 'maybe' as 'secure' value of a cookie"),
+		Entry("unknown cookie key", `cookies: [ { name: x, value: y, expires: 3600 } ]`, "3:35: tab 'Session': 'expires' is not a known key for a cookie"),
+		Entry("storage item that is not a string", `localStorage: { items: [ 1 ] }`, "3:26: tab 'Session': localStorage item 'items' must be a string, a number or a map"),
+	)
+
+	It("requires a domain for cookies of a tab without URL", func() {
+		_, err := script.Parse([]byte(`
+- name: Nowhere
+  cookies:
+    - name: session
+      value: x
+  script:
+    - wait: css:#login
+`))
+		Expect(err).To(MatchError("4:5: tab 'Nowhere': cookies need a 'domain' if the tab has no 'go' step"))
+	})
+
+	It("requires an absolute URL for headers", func() {
+		_, err := script.Parse([]byte(`
+- name: Nowhere
+  headers:
+    X-Kiosk: lobby
+  script:
+    - wait: css:#login
+`))
+		Expect(err).To(MatchError("4:5: tab 'Nowhere': headers need the tab to go to an absolute URL first, not ''"))
+	})
+
+	It("requires an absolute URL for storage", func() {
+		_, err := script.Parse([]byte(`
+- name: Relative
+  script:
+    - go: /dashboard
+  sessionStorage:
+    mode: kiosk
+`))
+		Expect(err).To(MatchError("6:5: tab 'Relative': storage needs the tab to go to an absolute URL first, not '/dashboard'"))
+	})
+
+	It("round-trips", func() {
+		tabs := []*script.Tab{{
+			Name:           "Dashboard",
+			Headers:        map[string]script.Secret{"X-Kiosk": script.ParseSecret("lobby"), "Authorization": script.ParseSecret("env:TOKEN")},
+			Cookies:        []script.Cookie{{Name: "session", Value: script.ParseSecret("file:session"), Secure: true}},
+			LocalStorage:   map[string]script.Secret{"token": script.ParseSecret("env:TOKEN")},
+			SessionStorage: map[string]script.Secret{"$price": script.ParseSecret("$5")},
+			Steps:          []script.Step{script.Go("https://example.com")},
+		}}
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal(`- name: Dashboard
+  headers:
+    Authorization: env:TOKEN
+    X-Kiosk: lobby
+  cookies:
+    - name: session
+      value: file:session
+      secure: true
+  localStorage:
+    token: env:TOKEN
+  sessionStorage:
+    $price: $5
+  script:
+    - go: https://example.com
+`))
+
+		parsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(tabs))
+	})
+})
@@ -0,0 +1,128 @@
+package script_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Step", func() {
+	parse := func(step string) ([]*script.Tab, error) {
+		return script.Parse([]byte(`
+- name: Steps
+  script:
+    - ` + step + `
+`))
+	}
+
+	DescribeTable("malformed",
+		func(step string, expected string) {
+			tabs, err := parse(step)
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("scalar",
+			`go`,
+			"4:7: tab 'Steps', step 1: unable to parse 'go' as step"),
+		Entry("list",
+			`[ go, https://example.com ]`,
+			"4:7: tab 'Steps', step 1: unable to parse '[go https://example.com]' as step"),
+		Entry("empty map",
+			`{}`,
+			"4:7: tab 'Steps', step 1: a step must have exactly one action, but has none"),
+		Entry("two actions",
+			`{ go: https://example.com, click: button }`,
+			"4:34: tab 'Steps', step 1: a step must have exactly one action, but has 'go' and 'click'"),
+		Entry("two actions in reverse order",
+			`{ click: button, go: https://example.com }`,
+			"4:24: tab 'Steps', step 1: a step must have exactly one action, but has 'click' and 'go'"),
+		Entry("action and unknown key",
+			`{ wait: foo, retry: 3 }`,
+			"4:20: tab 'Steps', step 1: a step must have exactly one action, but has 'wait' and 'retry'"),
+		Entry("unknown action",
+			`{ jump: foo }`,
+			"4:9: tab 'Steps', step 1: 'jump' is not a known step"),
+		Entry("duplicate action",
+			`{ click: a, click: b }`,
+			"4:19: tab 'Steps', step 1: 'click' is given more than once"),
+		Entry("only a modifier",
+			`{ timeout: 5s }`,
+			"4:7: tab 'Steps', step 1: a step must have exactly one action, but has none"),
+		Entry("duplicate modifier",
+			`{ click: a, timeout: 5s, timeout: 6s }`,
+			"4:32: tab 'Steps', step 1: 'timeout' is given more than once"),
+		Entry("then without if",
+			`{ then: [ { click: a } ] }`,
+			"4:9: tab 'Steps', step 1: 'then' is only allowed together with 'if'"),
+		Entry("else with another action",
+			`{ click: a, else: [ { click: b } ] }`,
+			"4:19: tab 'Steps', step 1: 'else' is only allowed together with 'if', not with 'click'"),
+		Entry("use with a modifier",
+			`{ use: login, timeout: 5s }`,
+			"4:21: tab 'Steps', step 1: 'timeout' cannot be applied to a Use step"),
+		Entry("use with another action",
+			`{ use: login, click: a }`,
+			"4:21: tab 'Steps', step 1: a step must have exactly one action, but has 'use' and 'click'"),
+		Entry("unparseable timeout",
+			`{ click: a, timeout: soon }`,
+			"4:28: tab 'Steps', step 1: unable to parse 'soon' as timeout: time: invalid duration \"soon\""),
+		Entry("numeric timeout",
+			`{ click: a, timeout: 5 }`,
+			"4:28: tab 'Steps', step 1: unable to parse '5' as timeout"),
+		Entry("negative timeout",
+			`{ click: a, timeout: -5s }`,
+			"4:28: tab 'Steps', step 1: timeout must be positive"),
+		Entry("type with both xpath and selector",
+			`{ type: { xpath: a, selector: b, value: c } }`,
+			"4:27: tab 'Steps', step 1: only one of 'xpath' and 'selector' may be given for a Type step"),
+		Entry("type with both value and secret",
+			`{ type: { xpath: a, value: b, secret: c } }`,
+			"4:15: tab 'Steps', step 1: only one of 'value' and 'secret' may be given for a Type step"),
+		Entry("type with unknown key",
+			`{ type: { xpath: a, value: b, delay: 1s } }`,
+			"4:37: tab 'Steps', step 1: 'delay' is not a known key for a Type step"),
+		Entry("type as list",
+			`{ type: [ a, b ] }`,
+			"4:15: tab 'Steps', step 1: unable to parse '[a b]' as value of a Type step"),
+		Entry("click with numeric selector",
+			`{ click: 42 }`,
+			"4:16: tab 'Steps', step 1: unable to parse '42' as value of a Click step"),
+	)
+
+	Context("with a timeout", func() {
+		var tabs []*script.Tab
+		var err error
+
+		BeforeEach(func() {
+			tabs, err = parse(`{ wait: css:.dashboard, timeout: 30s }`)
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("wraps the step", func() {
+			timeout, ok := tabs[0].Steps[0].(*script.Timeout)
+			Expect(ok).To(BeTrue())
+			Expect(timeout.Duration).To(Equal(30 * time.Second))
+			Expect(timeout.Step).To(Equal(script.Wait{Selector: script.Selector{Query: ".dashboard", By: script.ByCSS}}))
+		})
+
+		It("presents itself as expected", func() {
+			Expect(tabs[0].Steps[0].String()).To(Equal("wait for the element addressed by css '.dashboard' within 30s"))
+		})
+	})
+
+	Context("if with a timeout", func() {
+		It("wraps the whole construct", func() {
+			tabs, err := parse(`{ if: { text: Login }, then: [ { click: a } ], timeout: 10s }`)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeout, ok := tabs[0].Steps[0].(*script.Timeout)
+			Expect(ok).To(BeTrue())
+			Expect(timeout.Step).To(BeAssignableToTypeOf(&script.If{}))
+		})
+	})
+})
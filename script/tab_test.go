@@ -0,0 +1,94 @@
+package script_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Tab settings", func() {
+	It("parses", func() {
+		tabs, err := script.NewParser().WithVariable("zoom", "1.5").Parse([]byte(`
+- name: Lobby
+  zoom: ${zoom}
+  viewport:
+    width: 1920
+    height: 1080
+    deviceScaleFactor: 2
+  userAgent: Mozilla/5.0 (X11; Linux aarch64) Kiosk
+  colorScheme: dark
+  script:
+    - go: https://example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs).To(HaveLen(1))
+		Expect(tabs[0].Zoom).To(Equal(1.5))
+		Expect(tabs[0].Viewport).To(Equal(script.Viewport{Width: 1920, Height: 1080, DeviceScaleFactor: 2}))
+		Expect(tabs[0].UserAgent).To(Equal("Mozilla/5.0 (X11; Linux aarch64) Kiosk"))
+		Expect(tabs[0].ColorScheme).To(Equal(script.ColorSchemeDark))
+	})
+
+	It("has no settings by default", func() {
+		tabs, err := script.Parse([]byte(`
+- name: Plain
+  script:
+    - go: https://example.com
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tabs[0].Zoom).To(BeZero())
+		Expect(tabs[0].Viewport.IsZero()).To(BeTrue())
+		Expect(tabs[0].UserAgent).To(BeEmpty())
+		Expect(tabs[0].ColorScheme).To(Equal(script.ColorSchemeDefault))
+	})
+
+	DescribeTable("rejected",
+		func(setting string, expected string) {
+			tabs, err := script.Parse([]byte(`
+- name: Settings
+  ` + setting + `
+  script:
+    - go: https://example.com
+`))
+			Expect(err).To(MatchError(expected))
+			Expect(tabs).To(BeEmpty())
+		},
+		Entry("zoom that is not a number", `zoom: large`, "3:9: tab 'Settings': unable to parse 'large' as zoom"),
+		Entry("zero zoom", `zoom: 0`, "3:9: tab 'Settings': zoom must be positive"),
+		Entry("negative zoom", `zoom: -2`, "3:9: tab 'Settings': zoom must be positive"),
+		Entry("viewport that is not a map", `viewport: 1920x1080`, "3:13: tab 'Settings': unable to parse '1920x1080' as viewport"),
+		Entry("fractional width", `viewport: { width: 19.5 }`, "3:22: tab 'Settings': 'width' of a viewport must be a whole number"),
+		Entry("negative height", `viewport: { height: -1 }`, "3:13: tab 'Settings': width and height of the viewport must not be negative"),
+		Entry("unknown viewport key", `viewport: { depth: 3 }`, "3:15: tab 'Settings': 'depth' is not a known key for a viewport"),
+		Entry("user agent that is not a string", `userAgent: [ a, b ]`, "3:14: tab 'Settings': unable to parse '[a b]' as user agent"),
+		Entry("unknown color scheme", `colorScheme: sepia`, "3:16: tab 'Settings': 'sepia' is not a known color scheme"),
+	)
+
+	It("round-trips", func() {
+		tabs := []*script.Tab{{
+			Name:        "Touch",
+			Zoom:        0.75,
+			Viewport:    script.Viewport{Width: 800, Height: 480, DeviceScaleFactor: 1.5},
+			UserAgent:   "Kiosk/1.0",
+			ColorScheme: script.ColorSchemeLight,
+			Steps:       []script.Step{script.Go("https://example.com")},
+		}}
+
+		marshalled, err := script.Marshal(tabs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(Equal(`- name: Touch
+  zoom: 0.75
+  viewport:
+    width: 800
+    height: 480
+    deviceScaleFactor: 1.5
+  userAgent: Kiosk/1.0
+  colorScheme: light
+  script:
+    - go: https://example.com
+`))
+
+		parsed, err := script.Parse(marshalled)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(tabs))
+	})
+})
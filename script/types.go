@@ -1,16 +1,63 @@
 package script
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/chromedp/chromedp"
 )
 
 type Tab struct {
-	Name     string                   `yaml:"name"`
-	RawSteps []map[string]interface{} `yaml:"script"`
-	Steps    []Step
+	Name    string      `yaml:"name"`
+	OnError ErrorPolicy `yaml:"onError"`
+	// Zoom scales the content of the tab; 0 and 1 leave it as it is.
+	Zoom        float64     `yaml:"zoom"`
+	Viewport    Viewport    `yaml:"viewport"`
+	UserAgent   string      `yaml:"userAgent"`
+	ColorScheme ColorScheme `yaml:"colorScheme"`
+	// Headers are sent with the requests of the tab to its origin, or the
+	// ones of its panes.
+	Headers        map[string]Secret `yaml:"headers"`
+	Cookies        []Cookie          `yaml:"cookies"`
+	LocalStorage   map[string]Secret `yaml:"localStorage"`
+	SessionStorage map[string]Secret `yaml:"sessionStorage"`
+	Auth           *Auth             `yaml:"auth"`
+	Block          []*BlockRule      `yaml:"block"`
+	Rewrite        []*RewriteRule    `yaml:"rewrite"`
+	// Layout shows the pages of several panes instead of running steps.
+	Layout *Layout `yaml:"layout"`
+	// Media shows a local file or folder instead of running steps.
+	Media *Media
+	// Announcement shows a message instead of running steps.
+	Announcement *Announcement
+	Steps        []Step
+}
+
+// Setup prepares the browser for the steps of the tab.
+func (n *Tab) Setup() []chromedp.Action {
+	return []chromedp.Action{n.Emulation(), n.Session()}
+}
+
+// URL returns the target of the first Go step, or an empty string if there
+// is none. For a layout, it is the one of the first pane.
+func (n *Tab) URL() string {
+	if n.Layout != nil && len(n.Layout.Panes) > 0 {
+		return n.Layout.Panes[0].URL()
+	}
+
+	return firstURL(n.Steps)
+}
+
+func firstURL(steps []Step) string {
+	for _, step := range steps {
+		if g, ok := step.(Go); ok {
+			return string(g)
+		}
+	}
+
+	return ""
 }
 
 func (n *Tab) Actions() []chromedp.Action {
@@ -27,6 +74,30 @@ func (n *Tab) String() string {
 	return fmt.Sprintf("%v (%v actions)", n.Name, len(n.Actions()))
 }
 
+// ErrorPolicy determines what happens when the steps of a tab fail.
+type ErrorPolicy string
+
+const (
+	// OnErrorAbort stops the kiosk. This is the default.
+	OnErrorAbort ErrorPolicy = "abort"
+	// OnErrorSkip leaves the tab out of the rotation.
+	OnErrorSkip ErrorPolicy = "skip"
+	// OnErrorPlaceholder shows an error page in place of the tab and keeps
+	// retrying in the background until the steps succeed.
+	OnErrorPlaceholder ErrorPolicy = "placeholder"
+	// OnErrorRetry retries the steps a few times before giving up like OnErrorAbort.
+	OnErrorRetry ErrorPolicy = "retry"
+)
+
+func (p ErrorPolicy) Validate() error {
+	switch p {
+	case "", OnErrorAbort, OnErrorSkip, OnErrorPlaceholder, OnErrorRetry:
+		return nil
+	default:
+		return fmt.Errorf("'%v' is not a known error policy", string(p))
+	}
+}
+
 type Step interface {
 	Action() chromedp.Action
 	String() string
@@ -52,17 +123,26 @@ func (g Go) Validate() error {
 }
 
 type Type struct {
-	XPath  string `yaml:"xpath"`
+	Selector
 	Value  string `yaml:"value"`
-	Secret string `yaml:"secret"`
+	Secret Secret `yaml:"secret"`
 }
 
 func (t *Type) Action() chromedp.Action {
 	if t.Value != "" {
-		return chromedp.SendKeys(t.XPath, t.Value)
-	} else {
-		return chromedp.SendKeys(t.XPath, t.Secret)
+		return chromedp.SendKeys(t.expression(), t.Value, t.QueryOptions()...)
 	}
+
+	// resolve as late as possible so that the secret is held in memory only briefly
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		secret, err := t.Secret.Resolve()
+
+		if err != nil {
+			return err
+		}
+
+		return chromedp.SendKeys(t.expression(), secret, t.QueryOptions()...).Do(ctx)
+	})
 }
 
 func (t *Type) String() string {
@@ -71,57 +151,87 @@ func (t *Type) String() string {
 	if t.Value != "" {
 		value = fmt.Sprintf("'%v'", t.Value)
 	} else {
-		value = "the secret"
+		value = t.Secret.String()
 	}
 
-	return fmt.Sprintf("type %v into the element addressed by '%v'", value, t.XPath)
+	return fmt.Sprintf("type %v into the element addressed by %v", value, t.Selector)
 }
 
 func (t *Type) Validate() error {
-	if t.XPath == "" {
-		return errors.New("value for xpath must not be empty")
+	if t.Query == "" {
+		return errors.New("value for selector must not be empty")
+	}
+
+	if err := t.By.Validate(); err != nil {
+		return err
 	}
 
-	if t.Value == "" && t.Secret == "" {
+	if t.Value == "" && t.Secret.IsZero() {
 		return errors.New("either value or secret must be provided and not be empty")
 	}
 
+	if t.Value == "" {
+		return t.Secret.Validate()
+	}
+
 	return nil
 }
 
-type Click string
+type Click struct {
+	Selector
+}
 
 func (c Click) Action() chromedp.Action {
-	return chromedp.Click(string(c), chromedp.NodeVisible)
+	return chromedp.Click(c.expression(), append(c.QueryOptions(), chromedp.NodeVisible)...)
 }
 
 func (c Click) String() string {
-	return fmt.Sprintf("click the element addressed by '%v'", string(c))
+	return fmt.Sprintf("click the element addressed by %v", c.Selector)
 }
 
 func (c Click) Validate() error {
-	if c == "" {
-		return errors.New("value must not be empty")
-	}
-
-	return nil
+	return c.Selector.Validate()
 }
 
-type Wait string
+type Wait struct {
+	Selector
+}
 
 func (w Wait) Action() chromedp.Action {
-	return chromedp.WaitVisible(string(w))
+	return chromedp.WaitVisible(w.expression(), w.QueryOptions()...)
 }
 
 func (w Wait) String() string {
-	return fmt.Sprintf("wait for the element addressed by '%v'", string(w))
-
+	return fmt.Sprintf("wait for the element addressed by %v", w.Selector)
 }
 
 func (w Wait) Validate() error {
-	if w == "" {
-		return errors.New("value must not be empty")
+	return w.Selector.Validate()
+}
+
+// Timeout limits how long a step may take.
+type Timeout struct {
+	Step
+	Duration time.Duration
+}
+
+func (t *Timeout) Action() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, t.Duration)
+		defer cancel()
+
+		return t.Step.Action().Do(ctx)
+	})
+}
+
+func (t *Timeout) String() string {
+	return fmt.Sprintf("%v within %v", t.Step, t.Duration)
+}
+
+func (t *Timeout) Validate() error {
+	if t.Duration <= 0 {
+		return errors.New("timeout must be positive")
 	}
 
-	return nil
+	return t.Step.Validate()
 }
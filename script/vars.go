@@ -0,0 +1,148 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// variables holds the values available for ${name} interpolation. Besides
+// the ones defined in the script, ${hostname} and ${env:NAME} are available.
+type variables map[string]string
+
+func newVariables() variables {
+	vars := make(variables)
+
+	if hostname, err := os.Hostname(); err == nil {
+		vars["hostname"] = hostname
+	}
+
+	return vars
+}
+
+// secretKeys are the keys whose values are secrets. Secrets are taken as
+// written instead of being interpolated, so that a '$' in a password stays
+// as it is, and a resolved reference never ends up where the script is
+// written out again.
+var secretKeys = map[string]bool{
+	"secret":         true,
+	"password":       true,
+	"headers":        true,
+	"localStorage":   true,
+	"sessionStorage": true,
+}
+
+// interpolate replaces all references in s. A literal '$' is written as '$$'.
+func (v variables) interpolate(s string) (string, error) {
+	var result strings.Builder
+
+	for {
+		i := strings.IndexByte(s, '$')
+
+		if i < 0 || i == len(s)-1 {
+			result.WriteString(s)
+			return result.String(), nil
+		}
+
+		result.WriteString(s[:i])
+
+		switch s[i+1] {
+		case '$':
+			result.WriteByte('$')
+			s = s[i+2:]
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+
+			if end < 0 {
+				// the rest of s is not shown, as it may be part of a secret
+				return "", errors.New("unterminated variable reference")
+			}
+
+			value, err := v.lookup(s[i+2 : i+end])
+
+			if err != nil {
+				return "", err
+			}
+
+			result.WriteString(value)
+			s = s[i+end+1:]
+		default:
+			result.WriteByte('$')
+			s = s[i+1:]
+		}
+	}
+}
+
+func (v variables) lookup(name string) (string, error) {
+	if envName, found := strings.CutPrefix(name, "env:"); found {
+		value, found := os.LookupEnv(envName)
+
+		if !found {
+			return "", fmt.Errorf("environment variable '%v' is not set", envName)
+		}
+
+		return value, nil
+	}
+
+	value, found := v[name]
+
+	if !found {
+		return "", fmt.Errorf("undefined variable '%v'", name)
+	}
+
+	return value, nil
+}
+
+// hasReferences tells whether a scalar value below n, other than a secret,
+// refers to a variable.
+func hasReferences(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		// '$$' is an escaped '$', which does not start a reference
+		return strings.Contains(strings.ReplaceAll(n.Value, "$$", ""), "${")
+	case yaml.DocumentNode, yaml.SequenceNode:
+		return slices.ContainsFunc(n.Content, hasReferences)
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if !secretKeys[n.Content[i-1].Value] && hasReferences(n.Content[i]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// interpolateNode replaces the references in all scalar values below n, in
+// place, except for secrets. It returns a problem for every reference that
+// cannot be resolved.
+func (v variables) interpolateNode(n *yaml.Node) (errs []*Error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		interpolated, err := v.interpolate(n.Value)
+
+		if err != nil {
+			return []*Error{ErrorAt(n, "%v", err)}
+		}
+
+		n.Value = interpolated
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			errs = append(errs, v.interpolateNode(item)...)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if secretKeys[n.Content[i-1].Value] {
+				continue
+			}
+
+			errs = append(errs, v.interpolateNode(n.Content[i])...)
+		}
+	}
+
+	return errs
+}
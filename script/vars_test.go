@@ -0,0 +1,168 @@
+package script_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/script"
+)
+
+var _ = Describe("Variables", func() {
+	var parser *script.Parser
+	var scrpt []byte
+	var err error
+	var tabs []*script.Tab
+
+	BeforeEach(func() {
+		parser = script.NewParser()
+	})
+
+	JustBeforeEach(func() {
+		tabs, err = parser.Parse(scrpt)
+	})
+
+	Context("defined in the script", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("KIOSK_TEST_HOST", "grafana.example.com")
+
+			scrpt = []byte(`
+vars:
+  dashboard: 42
+  host: ${env:KIOSK_TEST_HOST}
+tabs:
+  - name: Dashboard ${dashboard}
+    script:
+      - go: https://${host}/d/${dashboard}?kiosk
+      - wait: //div[@id='panel-${dashboard}']
+      - type:
+          xpath: search
+          value: costs $$5 on ${hostname}
+`)
+		})
+
+		It("parses", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("interpolates the tab name", func() {
+			Expect(tabs[0].Name).To(Equal("Dashboard 42"))
+		})
+
+		It("interpolates the steps", func() {
+			Expect(tabs[0].Steps[0].String()).To(Equal("go to https://grafana.example.com/d/42?kiosk"))
+			Expect(tabs[0].Steps[1].String()).To(Equal("wait for the element addressed by '//div[@id='panel-42']'"))
+		})
+
+		It("provides the hostname and escapes the dollar sign", func() {
+			hostname, _ := os.Hostname()
+			Expect(tabs[0].Steps[2].String()).To(Equal("type 'costs $5 on " + hostname + "' into the element addressed by 'search'"))
+		})
+
+		Context("overridden", func() {
+			BeforeEach(func() {
+				parser = parser.WithVariable("dashboard", "7")
+			})
+
+			It("prefers the override", func() {
+				Expect(tabs[0].Steps[0].String()).To(Equal("go to https://grafana.example.com/d/7?kiosk"))
+			})
+		})
+	})
+
+	Context("undefined variable", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Dashboard
+  script:
+    - go: https://example.com/d/${dashboard}
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("4:11: tab 'Dashboard', step 1: undefined variable 'dashboard'"))
+		})
+
+		It("has no tabs", func() {
+			Expect(tabs).To(BeEmpty())
+		})
+	})
+
+	Context("unset environment variable", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Dashboard
+  script:
+    - go: https://${env:KIOSK_TEST_UNSET}/
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("4:11: tab 'Dashboard', step 1: environment variable 'KIOSK_TEST_UNSET' is not set"))
+		})
+	})
+
+	Context("in secrets", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+vars:
+  domain: example.com
+tabs:
+  - name: Dashboard
+    headers:
+      X-Key: pa$$word
+    cookies:
+      - name: session
+        value: $$ecret
+        domain: ${domain}
+    auth:
+      username: jdoe
+      password: pa$$word
+    script:
+      - go: https://${domain}
+      - type:
+          xpath: pin
+          secret: 12$$34
+`)
+		})
+
+		It("takes them as written", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tabs[0].Headers["X-Key"].Resolve()).To(Equal("pa$$word"))
+			Expect(tabs[0].Cookies[0].Value.Resolve()).To(Equal("$$ecret"))
+			Expect(tabs[0].Cookies[0].Domain).To(Equal("example.com"))
+			Expect(tabs[0].Auth.Password.Resolve()).To(Equal("pa$$word"))
+			Expect(tabs[0].Steps[1].(*script.Type).Secret.Resolve()).To(Equal("12$$34"))
+		})
+	})
+
+	Context("referenced in a secret", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Dashboard
+  script:
+    - type:
+        xpath: password
+        secret: ab${env:PASSWORD}
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("6:17: tab 'Dashboard', step 1: secret of a Type step is taken as written; reference an environment variable as 'env:NAME', or give a literal containing '${' as 'value'"))
+		})
+	})
+
+	Context("unterminated reference", func() {
+		BeforeEach(func() {
+			scrpt = []byte(`
+- name: Dashboard
+  script:
+    - go: https://${host/
+`)
+		})
+
+		It("does not parse", func() {
+			Expect(err).To(MatchError("4:11: tab 'Dashboard', step 1: unterminated variable reference"))
+		})
+	})
+})
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/videocore"
+)
+
+// takeover makes all screens show the same page or message, e.g. during an
+// incident, and switches the displays on. Handing back restores the tabs,
+// the tab switching and the backlight of before.
+type takeover struct {
+	mutex         sync.Mutex
+	kiosks        kiosks
+	displays      displays
+	logger        *log.Logger
+	statusUpdates chan controller.StatusUpdate
+	current       *controller.Takeover
+	expires       time.Time
+	expiry        *time.Timer
+	// generation tells an expiry whether the takeover it belongs to is still on.
+	generation int
+	// backlights is the backlight of each display before the takeover.
+	backlights []*videocore.DisplayStatus
+}
+
+// displays switches the backlight of the displays.
+type displays interface {
+	GetDisplays() ([]uint8, error)
+	GetBacklight(id uint8) (bool, error)
+	SetBacklight(id uint8, status bool) (bool, error)
+}
+
+// videocoreDisplays are the displays of a Raspberry Pi.
+type videocoreDisplays struct{}
+
+func (videocoreDisplays) GetDisplays() ([]uint8, error) {
+	return videocore.GetDisplays()
+}
+
+func (videocoreDisplays) GetBacklight(id uint8) (bool, error) {
+	return videocore.GetBacklight(id)
+}
+
+func (videocoreDisplays) SetBacklight(id uint8, status bool) (bool, error) {
+	return videocore.SetBacklight(id, status)
+}
+
+// takeoverJSON describes the current takeover.
+type takeoverJSON struct {
+	URL     string     `json:"url,omitempty"`
+	Message string     `json:"message,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+func createTakeoverHandler(kiosks kiosks, logger *log.Logger, statusUpdates chan controller.StatusUpdate) http.HandlerFunc {
+	t := &takeover{kiosks: kiosks, displays: videocoreDisplays{}, logger: logger, statusUpdates: statusUpdates}
+
+	return t.ServeHTTP
+}
+
+func (t *takeover) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		if t.current == nil {
+			http.Error(w, `{"error": "no takeover"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.describe())
+	case http.MethodPost:
+		t.post(w, r)
+	case http.MethodDelete:
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		if t.current == nil {
+			http.Error(w, `{"error": "no takeover"}`, http.StatusNotFound)
+			return
+		}
+
+		if err := t.handBack(); err != nil {
+			t.logger.Printf("could not hand back: %v", err)
+			http.Error(w, `{"error": "could not hand back all screens"}`, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, `{"error": "Only GET, POST or DELETE allowed here"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *takeover) post(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		t.logger.Printf("could not parse form parameters: %v", err)
+		http.Error(w, `{"error": "could not parse form parameters"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	requested, expiry, err := parseTakeover(r)
+
+	if err != nil {
+		t.logger.Println(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.logger.Printf("taking over all screens with %v", requested)
+
+	if t.current == nil {
+		t.switchBacklightOn()
+	}
+
+	t.current = &requested
+	t.generation++
+	var errs []error
+
+	for _, kiosk := range t.kiosks {
+		if err := kiosk.TakeOver(requested); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", describeScreen(kiosk), err))
+		}
+	}
+
+	if t.expiry != nil {
+		t.expiry.Stop()
+		t.expiry = nil
+		t.expires = time.Time{}
+	}
+
+	if expiry > 0 {
+		t.expires = time.Now().Add(expiry)
+		generation := t.generation
+		t.expiry = time.AfterFunc(expiry, func() { t.expire(generation) })
+	}
+
+	// the screens that were taken over are handed back by DELETE, too
+	if err := errors.Join(errs...); err != nil {
+		t.logger.Printf("could not take over: %v", err)
+		http.Error(w, `{"error": "could not take over all screens"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t.describe())
+}
+
+// parseTakeover reads either 'url' or 'message' from the request, and the
+// optional 'expiry' after which the screens are handed back.
+func parseTakeover(r *http.Request) (controller.Takeover, time.Duration, error) {
+	requested := controller.Takeover{URL: r.FormValue("url"), Message: r.FormValue("message")}
+
+	if (requested.URL == "") == (requested.Message == "") {
+		return requested, 0, errors.New("a takeover needs either 'url' or 'message'")
+	}
+
+	if requested.URL != "" {
+		u, err := url.Parse(requested.URL)
+
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return requested, 0, fmt.Errorf("'%v' is not an absolute HTTP URL", requested.URL)
+		}
+	}
+
+	var expiry time.Duration
+
+	if value := r.FormValue("expiry"); value != "" {
+		var err error
+		expiry, err = time.ParseDuration(value)
+
+		if err != nil || expiry <= 0 {
+			return requested, 0, fmt.Errorf("unable to parse '%v' as expiry; expecting a positive duration like '30m'", value)
+		}
+	}
+
+	return requested, expiry, nil
+}
+
+// expire hands back the screens once the takeover expires.
+func (t *takeover) expire(generation int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// handed back or taken over anew in the meantime
+	if t.current == nil || t.generation != generation {
+		return
+	}
+
+	t.logger.Printf("the takeover with %v expired", t.current)
+
+	if err := t.handBack(); err != nil {
+		t.logger.Printf("could not hand back: %v", err)
+	}
+}
+
+// handBack restores what the screens and displays showed before the
+// takeover. The caller holds the mutex.
+func (t *takeover) handBack() error {
+	t.logger.Printf("handing back all screens")
+
+	if t.expiry != nil {
+		t.expiry.Stop()
+	}
+
+	t.current = nil
+	t.expiry = nil
+	t.expires = time.Time{}
+
+	var errs []error
+
+	for _, kiosk := range t.kiosks {
+		if !kiosk.IsTakenOver() {
+			continue
+		}
+
+		if err := kiosk.HandBack(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", describeScreen(kiosk), err))
+		}
+	}
+
+	t.restoreBacklight()
+
+	return errors.Join(errs...)
+}
+
+// switchBacklightOn remembers the backlight of the displays, and switches it
+// on. Without displays to switch, e.g. on a machine other than a Raspberry
+// Pi, the backlight is left alone.
+func (t *takeover) switchBacklightOn() {
+	backlights, err := eachDisplayOf(t.displays.GetDisplays, t.displays.GetBacklight)
+
+	if err != nil {
+		t.logger.Printf("leaving the backlight alone: %v", err)
+		t.backlights = nil
+		return
+	}
+
+	t.backlights = backlights
+
+	displayStati, err := eachDisplayOf(t.displays.GetDisplays, func(id uint8) (bool, error) {
+		return t.displays.SetBacklight(id, true)
+	})
+
+	if err != nil {
+		t.logger.Printf("could not switch the backlight on: %v", err)
+	}
+
+	t.notify(controller.StatusUpdate{DisplayStati: displayStati})
+}
+
+// restoreBacklight switches each display back to its backlight of before the takeover.
+func (t *takeover) restoreBacklight() {
+	if t.backlights == nil {
+		return
+	}
+
+	var displayStati []*videocore.DisplayStatus
+
+	for _, before := range t.backlights {
+		status, err := t.displays.SetBacklight(before.ID, before.Status)
+
+		if err != nil {
+			t.logger.Printf("could not restore the backlight of display %v: %v", before.ID, err)
+			continue
+		}
+
+		displayStati = append(displayStati, &videocore.DisplayStatus{ID: before.ID, Status: status})
+	}
+
+	t.backlights = nil
+	t.notify(controller.StatusUpdate{DisplayStati: displayStati})
+}
+
+// notify sends the update, unless the channel is full. Like the kiosks, the
+// takeover must not stall while nobody is listening.
+func (t *takeover) notify(update controller.StatusUpdate) {
+	select {
+	case t.statusUpdates <- update:
+	default:
+	}
+}
+
+func (t *takeover) describe() takeoverJSON {
+	described := takeoverJSON{URL: t.current.URL, Message: t.current.Message}
+
+	if !t.expires.IsZero() {
+		expires := t.expires
+		described.Expires = &expires
+	}
+
+	return described
+}
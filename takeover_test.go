@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/videocore"
+)
+
+// fakeDisplays remembers the backlight of each display instead of switching it.
+type fakeDisplays struct {
+	mutex      sync.Mutex
+	backlights map[uint8]bool
+	err        error
+}
+
+func (d *fakeDisplays) GetDisplays() ([]uint8, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return []uint8{2, 7}, nil
+}
+
+func (d *fakeDisplays) GetBacklight(id uint8) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.backlights[id], nil
+}
+
+func (d *fakeDisplays) SetBacklight(id uint8, status bool) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.backlights[id] = status
+
+	return status, nil
+}
+
+func (d *fakeDisplays) current() map[uint8]bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	current := make(map[uint8]bool)
+
+	for id, status := range d.backlights {
+		current[id] = status
+	}
+
+	return current
+}
+
+var _ = Describe("takeover", func() {
+	var displays *fakeDisplays
+	var statusUpdates chan controller.StatusUpdate
+	var t *takeover
+
+	request := func(method string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/takeover", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		t.ServeHTTP(w, r)
+
+		return w
+	}
+
+	BeforeEach(func() {
+		displays = &fakeDisplays{backlights: map[uint8]bool{2: false, 7: true}}
+		statusUpdates = make(chan controller.StatusUpdate, 10)
+		t = &takeover{displays: displays, logger: log.New(io.Discard, "", 0), statusUpdates: statusUpdates}
+	})
+
+	It("switches the backlight on until it is handed back", func() {
+		response := request(http.MethodPost, url.Values{"message": {"Please leave the building"}})
+		Expect(response.Code).To(Equal(http.StatusCreated))
+		Expect(response.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(response.Body.String()).To(MatchJSON(`{"message": "Please leave the building"}`))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: true, 7: true}))
+		Expect(statusUpdates).To(Receive(Equal(controller.StatusUpdate{DisplayStati: []*videocore.DisplayStatus{{ID: 2, Status: true}, {ID: 7, Status: true}}})))
+
+		response = request(http.MethodGet, nil)
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(MatchJSON(`{"message": "Please leave the building"}`))
+
+		response = request(http.MethodDelete, nil)
+		Expect(response.Code).To(Equal(http.StatusNoContent))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: false, 7: true}))
+		Expect(statusUpdates).To(Receive(Equal(controller.StatusUpdate{DisplayStati: []*videocore.DisplayStatus{{ID: 2, Status: false}, {ID: 7, Status: true}}})))
+
+		response = request(http.MethodGet, nil)
+		Expect(response.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("restores the backlight of before the first takeover", func() {
+		Expect(request(http.MethodPost, url.Values{"message": {"Fire drill"}}).Code).To(Equal(http.StatusCreated))
+		Expect(request(http.MethodPost, url.Values{"url": {"https://status.example.com/incident"}}).Code).To(Equal(http.StatusCreated))
+		Expect(request(http.MethodDelete, nil).Code).To(Equal(http.StatusNoContent))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: false, 7: true}))
+	})
+
+	It("hands back once it expires", func() {
+		response := request(http.MethodPost, url.Values{"message": {"Fire drill"}, "expiry": {"50ms"}})
+		Expect(response.Code).To(Equal(http.StatusCreated))
+		Expect(response.Body.String()).To(ContainSubstring(`"expires"`))
+
+		Eventually(func() int { return request(http.MethodGet, nil).Code }).Should(Equal(http.StatusNotFound))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: false, 7: true}))
+	})
+
+	It("does not expire once taken over anew", func() {
+		Expect(request(http.MethodPost, url.Values{"message": {"Fire drill"}, "expiry": {"50ms"}}).Code).To(Equal(http.StatusCreated))
+		Expect(request(http.MethodPost, url.Values{"message": {"Please leave the building"}}).Code).To(Equal(http.StatusCreated))
+
+		Consistently(func() int { return request(http.MethodGet, nil).Code }, 200*time.Millisecond).Should(Equal(http.StatusOK))
+	})
+
+	It("leaves the backlight alone without displays", func() {
+		displays.err = errors.New("no videocore")
+
+		Expect(request(http.MethodPost, url.Values{"message": {"Fire drill"}}).Code).To(Equal(http.StatusCreated))
+		Expect(request(http.MethodDelete, nil).Code).To(Equal(http.StatusNoContent))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: false, 7: true}))
+		Expect(statusUpdates).ToNot(Receive())
+	})
+
+	It("does not wait for anybody to read the status updates", func() {
+		statusUpdates = make(chan controller.StatusUpdate, 1)
+		statusUpdates <- controller.StatusUpdate{}
+		t.statusUpdates = statusUpdates
+
+		Expect(request(http.MethodPost, url.Values{"message": {"Fire drill"}}).Code).To(Equal(http.StatusCreated))
+		Expect(request(http.MethodDelete, nil).Code).To(Equal(http.StatusNoContent))
+		Expect(displays.current()).To(Equal(map[uint8]bool{2: false, 7: true}))
+	})
+
+	It("rejects a takeover without either a URL or a message", func() {
+		response := request(http.MethodPost, url.Values{"url": {"https://status.example.com"}, "message": {"Fire drill"}})
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"error": "a takeover needs either 'url' or 'message'"}`))
+	})
+})
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"uhlig.it/kiosk/controller"
+	"uhlig.it/kiosk/script"
+)
+
+type tryCommand struct {
+	Tab     string        `short:"t" long:"tab" description:"name of the tab to try; may be omitted if the script has only one tab"`
+	Format  string        `short:"f" long:"format" description:"format of the report" choice:"html" choice:"json" default:"html"`
+	Output  string        `short:"o" long:"output" description:"file to write the report to; '-' writes to STDOUT" default:"-"`
+	Timeout time.Duration `long:"timeout" description:"how long all steps of the tab may take together" default:"1m"`
+	Args    struct {
+		Scriptfile string `description:"script containing the tab; reads from STDIN if not given"`
+	} `positional-args:"yes"`
+}
+
+// Execute runs the steps of one tab in a headless browser and writes a report about them.
+func (c *tryCommand) Execute(args []string) error {
+	scriptBytes, err := readScript(c.Args.Scriptfile)
+
+	if err != nil {
+		return fmt.Errorf("could not read scriptfile: %w", err)
+	}
+
+	parser, err := newScriptParser(c.Args.Scriptfile)
+
+	if err != nil {
+		return err
+	}
+
+	tabs, err := parser.Parse(scriptBytes)
+
+	if err != nil {
+		return err
+	}
+
+	tab, err := findTab(tabs, c.Tab)
+
+	if err != nil {
+		return err
+	}
+
+	if opts.SecretsDir != "" {
+		script.SecretsDirectory = opts.SecretsDir
+	}
+
+	trial := controller.NewTrial().WithTimeout(c.Timeout)
+
+	for _, cf := range opts.ChromeFlags {
+		key, value, found := strings.Cut(cf, "=")
+
+		if !found {
+			return fmt.Errorf("could not separate chrome flag %v; expecting k=v", cf)
+		}
+
+		trial = trial.WithFlag(key, value)
+	}
+
+	report, err := trial.Run(tab)
+
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+
+	if c.Output != "-" {
+		f, err := os.Create(c.Output)
+
+		if err != nil {
+			return fmt.Errorf("could not create report: %w", err)
+		}
+
+		defer f.Close()
+		w = f
+	}
+
+	if c.Format == "json" {
+		err = report.WriteJSON(w)
+	} else {
+		err = report.WriteHTML(w)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+
+	if !report.Succeeded() {
+		return fmt.Errorf("tab '%v': %v", tab.Name, report.Error)
+	}
+
+	if opts.Verbose {
+		log.Printf("all %v steps of tab '%v' succeeded in %v\n", len(report.Steps), tab.Name, report.Duration)
+	}
+
+	return nil
+}
+
+// findTab returns the tab with the given name, or the only tab if name is empty.
+func findTab(tabs []*script.Tab, name string) (*script.Tab, error) {
+	var names []string
+
+	for _, tab := range tabs {
+		if tab.Name == name || (name == "" && len(tabs) == 1) {
+			return tab, nil
+		}
+
+		names = append(names, fmt.Sprintf("'%v'", tab.Name))
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("--tab is required for a script with %v tabs", len(tabs))
+	}
+
+	return nil, fmt.Errorf("there is no tab '%v'; expecting one of %v", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type validateCommand struct {
+	Args struct {
+		Scriptfiles []string `description:"scripts to validate; reads from STDIN if none are given"`
+	} `positional-args:"yes"`
+}
+
+// Execute checks the given scripts thoroughly, but without starting the browser.
+func (c *validateCommand) Execute(args []string) error {
+	paths := c.Args.Scriptfiles
+
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	invalid := 0
+
+	for _, path := range paths {
+		name := path
+
+		if name == "" || name == "-" {
+			name = "STDIN"
+		}
+
+		scriptBytes, err := readScript(path)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: could not read script: %v\n", name, err)
+			invalid++
+			continue
+		}
+
+		parser, err := newScriptParser(path)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = parser.WithThoroughChecks(true).Parse(scriptBytes)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			invalid++
+			continue
+		}
+
+		fmt.Printf("%v: OK\n", name)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%v of %v scripts are invalid", invalid, len(paths))
+	}
+
+	return nil
+}